@@ -0,0 +1,234 @@
+package tmplmgr
+
+import (
+	"context"
+	"html/template"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+//names of the functions recording the context paths looked up for ExecuteTrace.
+const (
+	accessFunc      = "_access"
+	accessEnterFunc = "_access_enter"
+	accessLeaveFunc = "_access_leave"
+)
+
+//ExecuteTrace executes the template like ExecuteString, also returning the
+//paths of the fields and map keys the executed actions looked up in the
+//context, such as "User.Name", in the order they were first looked up. Lookups
+//on the elements of a range are reported as "Items[].Title", and lookups in a
+//template invoked with a context that can't be told from the actions are
+//prefixed by its name, as in "header:Title". It is meant for debugging, as
+//every call parses the template again and works on a set of its own.
+//
+//There is no proxy wrapping the context: the actions are rewritten to report
+//the paths they look up before they run, so maps and structs are handled alike
+//and a path is reported even if the key is missing, which is usually the
+//answer to why something is empty. The other side of it is that only paths as
+//written are known. Struct methods are reported like fields, lookups on the
+//results of functions or of parenthesized pipelines, as in
+//(index .Items 0).Title, are not reported, and paths beneath a dot set from
+//such a pipeline start with "?".
+func (t *Template) ExecuteTrace(ctx interface{}, globs ...string) (output string, accessed []string, err error) {
+	var res Result
+	var buf strings.Builder
+	funcs, rec := accessFuncs()
+	err = t.renderWith(context.Background(), globs, &res, funcs, func(tmpl *template.Template) (err error) {
+		if ctx, err = t.prepare(ctx); err != nil {
+			return
+		}
+		if tmpl, err = accessCalls(tmpl); err != nil {
+			return
+		}
+
+		if t.filtering() {
+			_, err = t.executeFiltered(tmpl, &buf, ctx)
+			return
+		}
+		return tmpl.Execute(newCountWriter(&buf, t.max_output), ctx)
+	})
+	return buf.String(), *rec, err
+}
+
+//accessFuncs returns the functions recording the paths looked up by one
+//Execute into the returned slice. The enter and leave functions keep the path
+//of the context of every invoked template, which the paths looked up in it are
+//relative to.
+func accessFuncs() (template.FuncMap, *[]string) {
+	var accessed []string
+	seen := map[string]bool{}
+	stack := []string{""}
+	funcs := template.FuncMap{
+		accessFunc: func(paths ...string) bool {
+			for _, p := range paths {
+				p = joinPath(stack[len(stack)-1], p)
+				if !seen[p] {
+					seen[p] = true
+					accessed = append(accessed, p)
+				}
+			}
+			return false
+		},
+		accessEnterFunc: func(name, dot string) bool {
+			if dot == "?" {
+				stack = append(stack, name+":")
+			} else {
+				stack = append(stack, joinPath(stack[len(stack)-1], dot))
+			}
+			return false
+		},
+		accessLeaveFunc: func() bool {
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			return false
+		},
+	}
+	return funcs, &accessed
+}
+
+//accessCalls returns the set with copies of its trees in which every action
+//reports the paths it looks up first, and every template invocation is
+//surrounded with the enter and leave functions. The trees are copied as a
+//cloned set shares them with the set it was cloned from.
+func accessCalls(tmpl *template.Template) (*template.Template, error) {
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil {
+			continue
+		}
+		tree := x.Tree.Copy()
+		accessList(tree.Root, "", map[string]string{})
+		nx, err := tmpl.AddParseTree(x.Name(), tree)
+		if err != nil {
+			return nil, err
+		}
+		if x.Name() == tmpl.Name() {
+			tmpl = nx
+		}
+	}
+	return tmpl, nil
+}
+
+//accessList rewrites the nodes of the list, where dot is the path of dot
+//relative to the context of the template and vars holds the paths of the
+//variables that are known.
+func accessList(list *parse.ListNode, dot string, vars map[string]string) {
+	if list == nil {
+		return
+	}
+
+	var nodes []parse.Node
+	for _, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			nodes = accessPaths(nodes, n.Pipe, dot, vars)
+			if len(n.Pipe.Decl) == 1 {
+				vars[n.Pipe.Decl[0].Ident[0]] = pipePath(n.Pipe, dot, vars)
+			}
+		case *parse.IfNode:
+			nodes = accessPaths(nodes, n.Pipe, dot, vars)
+			accessList(n.List, dot, scopeVars(vars))
+			accessList(n.ElseList, dot, scopeVars(vars))
+		case *parse.RangeNode:
+			nodes = accessPaths(nodes, n.Pipe, dot, vars)
+			inner, scoped := pipePath(n.Pipe, dot, vars)+"[]", scopeVars(vars)
+			for i, d := range n.Pipe.Decl {
+				if i == len(n.Pipe.Decl)-1 {
+					scoped[d.Ident[0]] = inner
+				} else {
+					delete(scoped, d.Ident[0])
+				}
+			}
+			accessList(n.List, inner, scoped)
+			accessList(n.ElseList, dot, scopeVars(vars))
+		case *parse.WithNode:
+			nodes = accessPaths(nodes, n.Pipe, dot, vars)
+			inner, scoped := pipePath(n.Pipe, dot, vars), scopeVars(vars)
+			if len(n.Pipe.Decl) == 1 {
+				scoped[n.Pipe.Decl[0].Ident[0]] = inner
+			}
+			accessList(n.List, inner, scoped)
+			accessList(n.ElseList, dot, scopeVars(vars))
+		case *parse.TemplateNode:
+			inner := "?"
+			if n.Pipe != nil {
+				nodes = accessPaths(nodes, n.Pipe, dot, vars)
+				inner = pipePath(n.Pipe, dot, vars)
+			}
+			enter := accessEnterFunc + " " + strconv.Quote(n.Name) + " " + strconv.Quote(inner)
+			nodes = append(nodes, depthCall(enter), n, depthCall(accessLeaveFunc))
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	list.Nodes = nodes
+}
+
+//accessPaths appends a call reporting the paths looked up by the pipeline to
+//nodes, if it looks up any.
+func accessPaths(nodes []parse.Node, pipe *parse.PipeNode, dot string, vars map[string]string) []parse.Node {
+	var paths []string
+	walk(pipe, func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.FieldNode:
+			paths = append(paths, strconv.Quote(joinPath(dot, strings.Join(n.Ident, "."))))
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 {
+				paths = append(paths, strconv.Quote(varPath(n, vars)))
+			}
+		}
+	})
+	if len(paths) == 0 {
+		return nodes
+	}
+	return append(nodes, depthCall(accessFunc+" "+strings.Join(paths, " ")))
+}
+
+//pipePath returns the path of the value of the pipeline, or "?" if it is not
+//a plain lookup.
+func pipePath(pipe *parse.PipeNode, dot string, vars map[string]string) string {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "?"
+	}
+	switch n := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return joinPath(dot, strings.Join(n.Ident, "."))
+	case *parse.VariableNode:
+		return varPath(n, vars)
+	}
+	return "?"
+}
+
+//varPath returns the path of the variable lookup. Variables whose path is not
+//known are reported by their name.
+func varPath(n *parse.VariableNode, vars map[string]string) string {
+	base := n.Ident[0]
+	if base == "$" {
+		base = ""
+	} else if p, ok := vars[base]; ok {
+		base = p
+	}
+	return joinPath(base, strings.Join(n.Ident[1:], "."))
+}
+
+//scopeVars returns a copy of vars for a nested scope.
+func scopeVars(vars map[string]string) map[string]string {
+	out := make(map[string]string, len(vars))
+	for name, p := range vars {
+		out[name] = p
+	}
+	return out
+}
+
+//joinPath appends path to the path prefix. Paths of range elements, starting
+//with [], are appended without a dot.
+func joinPath(prefix, path string) string {
+	if prefix == "" || path == "" || strings.HasSuffix(prefix, ":") || strings.HasPrefix(path, "[]") {
+		return prefix + path
+	}
+	return prefix + "." + path
+}
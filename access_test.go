@@ -0,0 +1,75 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExecuteTrace(t *testing.T) {
+	type item struct{ Title string }
+	ctx := map[string]interface{}{
+		"User":  map[string]string{"Name": "u"},
+		"Items": []item{{"a"}, {"b"}},
+		"Page":  map[string]string{"Title": "p"},
+	}
+	cases := []struct {
+		name  string
+		base  string
+		files map[string]string
+		out   string
+		paths []string
+	}{
+		{
+			name:  "first lookup order",
+			base:  `{% .User.Name %}{% .Page.Title %}{% .User.Name %}`,
+			out:   "upu",
+			paths: []string{"User.Name", "Page.Title"},
+		},
+		{
+			name:  "range and variables",
+			base:  `{% range $i, $it := .Items %}{% .Title %}{% $it.Title %}{% end %}{% $u := .User %}{% $u.Name %}`,
+			out:   "aabbu",
+			paths: []string{"Items", "Items[].Title", "User", "User.Name"},
+		},
+		{
+			name:  "block with dot",
+			base:  `<h1>{% block "header" .Page %}{% .Title %}{% end %}</h1>`,
+			out:   "<h1>p</h1>",
+			paths: []string{"Page", "Page.Title"},
+		},
+		{
+			name:  "define from a glob replacing the block",
+			base:  `{% block "content" . %}none{% end %}`,
+			files: map[string]string{"c.tmpl": `{% define "content" %}{% .User.Name %}{% template "row" .Items %}{% end %}{% define "row" %}{% range . %}{% .Title %}{% end %}{% end %}`},
+			out:   "uab",
+			paths: []string{"User.Name", "Items", "Items[].Title"},
+		},
+		{
+			name:  "unknown dot",
+			base:  `{% template "t" (index .Items 0) %}{% define "t" %}{% .Title %}{% end %}`,
+			out:   "a",
+			paths: []string{"Items", "t:Title"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{"base.tmpl": c.base}
+			var globs []string
+			for name, data := range c.files {
+				files[name] = data
+				globs = append(globs, filepath.Join(dir, name))
+			}
+			writeFiles(t, dir, files)
+
+			out, paths, err := Parse(filepath.Join(dir, "base.tmpl")).ExecuteTrace(ctx, globs...)
+			if err != nil || out != c.out {
+				t.Fatalf("got %q, %v, want %q", out, err, c.out)
+			}
+			if !reflect.DeepEqual(paths, c.paths) {
+				t.Errorf("got paths %q, want %q", paths, c.paths)
+			}
+		})
+	}
+}
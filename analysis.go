@@ -0,0 +1,296 @@
+package tmplmgr
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+//UnusedDefines compiles every page and returns the sorted names of the
+//templates defined in the files matching the block globs that are never
+//invoked by a template or block action in any page or block. The blocks are
+//parsed with the delimiters of the first page.
+func UnusedDefines(pages []*Template, blocks ...string) (unused []string, err error) {
+	left, right := styles[StyleGoods][0], styles[StyleGoods][1]
+	if len(pages) > 0 {
+		left, right = pages[0].delims()
+	}
+
+	used := map[string]bool{}
+	for _, page := range pages {
+		if err = page.Compile(); err != nil {
+			return
+		}
+
+		page.compile_lock.RLock()
+		for _, x := range page.t.Templates() {
+			if x.Tree != nil {
+				references(x.Tree.Root, used)
+			}
+		}
+		page.compile_lock.RUnlock()
+	}
+
+	trees, err := parseGlobTrees(blocks, left, right)
+	if err != nil {
+		return
+	}
+	for _, tree := range trees {
+		references(tree.Root, used)
+	}
+
+	for name := range trees {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	return
+}
+
+//UnusedFuncs compiles the template with the globs attached and returns the
+//sorted names of the functions attached with Call and ScopedCall that no
+//template in the set calls. Functions from DefaultFuncs and html/template's
+//builtins are not reported.
+func (t *Template) UnusedFuncs(globs ...string) (unused []string, err error) {
+	var res Result
+	err = t.render(globs, &res, func(tmpl *template.Template) error {
+		used := map[string]bool{}
+		for _, x := range tmpl.Templates() {
+			if x.Tree == nil {
+				continue
+			}
+			walk(x.Tree.Root, func(node parse.Node) {
+				if n, ok := node.(*parse.IdentifierNode); ok {
+					used[n.Ident] = true
+				}
+			})
+		}
+
+		for name := range t.funcs {
+			if !used[name] {
+				unused = append(unused, name)
+			}
+		}
+		for i, g := range t.groups {
+			for name := range g.funcs {
+				if !used[scopedName(i, name)] {
+					unused = append(unused, name)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(unused)
+	return
+}
+
+//CheckReferences compiles the template with the globs attached and returns the
+//sorted names of the templates invoked by template and block actions that are
+//not defined in the set, which would only fail once an Execute reaches them.
+func (t *Template) CheckReferences(globs ...string) (missing []string, err error) {
+	var res Result
+	err = t.render(globs, &res, func(tmpl *template.Template) error {
+		used := map[string]bool{}
+		for _, x := range tmpl.Templates() {
+			if x.Tree != nil {
+				references(x.Tree.Root, used)
+			}
+		}
+		for name := range used {
+			if x := tmpl.Lookup(name); x == nil || x.Tree == nil {
+				missing = append(missing, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(missing)
+	return
+}
+
+//references adds the name of every template invoked beneath node to names.
+//The escaper of an executed set may point invocations at contextual copies of
+//a template, so those are reported as the original.
+func references(node parse.Node, names map[string]bool) {
+	walk(node, func(node parse.Node) {
+		if n, ok := node.(*parse.TemplateNode); ok {
+			name := n.Name
+			if i := strings.Index(name, "$htmltemplate_"); i >= 0 {
+				name = name[:i]
+			}
+			names[name] = true
+		}
+	})
+}
+
+//parseGlobTrees parses the files matching the globs without checking that the
+//functions they call exist, returning the trees of the templates they define
+//by name. The top level templates of the files themselves are left out.
+func parseGlobTrees(globs []string, left, right string) (trees map[string]*parse.Tree, err error) {
+	trees = map[string]*parse.Tree{}
+	for _, glob := range globs {
+		var files []string
+		if files, err = filepath.Glob(glob); err != nil {
+			return
+		}
+		for _, file := range files {
+			var data []byte
+			if data, err = ioutil.ReadFile(file); err != nil {
+				return
+			}
+
+			name := filepath.Base(file)
+			set := map[string]*parse.Tree{}
+			tree := parse.New(name)
+			tree.Mode = parse.SkipFuncCheck
+			if _, err = tree.Parse(string(data), left, right, set); err != nil {
+				return
+			}
+			delete(set, name)
+			for def, tree := range set {
+				trees[def] = tree
+			}
+		}
+	}
+	return
+}
+
+//Dependencies compiles the template with the globs attached and returns the
+//sorted names of the templates the base transitively invokes with template
+//and block actions. Names that are invoked but never defined are included.
+func (t *Template) Dependencies(globs ...string) (names []string, err error) {
+	deps, err := t.dependencies(globs)
+	if err != nil {
+		return
+	}
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+//DependencyFiles is like Dependencies but returns the sorted paths of the
+//files the dependencies are defined in, other than the base. Definitions whose
+//file can't be resolved are left out.
+func (t *Template) DependencyFiles(globs ...string) (files []string, err error) {
+	deps, err := t.dependencies(globs)
+	if err != nil {
+		return
+	}
+
+	t.compile_lock.RLock()
+	paths, err := t.filePaths(t.withDefaultGlobs(globs))
+	t.compile_lock.RUnlock()
+	if err != nil {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, tree := range deps {
+		if tree == nil {
+			continue
+		}
+		if path, ok := paths[tree.ParseName]; ok && path != t.base && !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+	return
+}
+
+//dependencies returns the trees of the templates the base transitively
+//invokes keyed by name, with nil trees for the ones that aren't defined.
+func (t *Template) dependencies(globs []string) (deps map[string]*parse.Tree, err error) {
+	var res Result
+	err = t.render(globs, &res, func(tmpl *template.Template) error {
+		deps = map[string]*parse.Tree{}
+		queue := []*parse.Tree{tmpl.Tree}
+		for len(queue) > 0 {
+			tree := queue[0]
+			queue = queue[1:]
+			if tree == nil {
+				continue
+			}
+
+			invoked := map[string]bool{}
+			references(tree.Root, invoked)
+			for name := range invoked {
+				if _, ex := deps[name]; ex || name == tmpl.Name() {
+					continue
+				}
+				var dep *parse.Tree
+				if x := tmpl.Lookup(name); x != nil {
+					dep = x.Tree
+				}
+				deps[name] = dep
+				queue = append(queue, dep)
+			}
+		}
+		return nil
+	})
+	return
+}
+
+//filePaths returns the paths of the base and every file matched by the globs
+//attached to the template or passed in, keyed by the name they are parsed
+//under. Later files win, as they do when parsing. The caller must hold at
+//least the read lock.
+func (t *Template) filePaths(globs []string) (paths map[string]string, err error) {
+	files, err := t.matchFiles(globs)
+	if err != nil {
+		return
+	}
+
+	paths = map[string]string{}
+	for _, file := range files {
+		paths[filepath.Base(file)] = file
+	}
+	return
+}
+
+//DumpTo compiles the template with the globs attached and writes the source of
+//every template defined in the set to dir, as <name>.tmpl with any slashes in
+//the name replaced by underscores, to show exactly what was merged. The sources
+//are printed back from the parse trees, so they show overridden definitions as
+//they ended up. Files holding nothing but definitions are left out.
+func (t *Template) DumpTo(dir string, globs ...string) (err error) {
+	if t.Dirty() {
+		if err = t.Compile(); err != nil {
+			return
+		}
+	}
+
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	//a set that was never executed doesn't have the escaper's changes
+	tmpl, err := t.parseExecGlobs(t.withDefaultGlobs(globs))
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil || x.Tree.Root == nil || parse.IsEmptyTree(x.Tree.Root) {
+			continue
+		}
+		name := strings.NewReplacer("/", "_", "\\", "_").Replace(x.Name()) + ".tmpl"
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(x.Tree.Root.String()), 0644); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,42 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUnusedDefines(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"home.tmpl":           `<main>{% block "content" . %}{% end %}</main>{% template "footer" . %}`,
+		"about.tmpl":          `{% template "header" . %}{% .Text %}`,
+		"blocks/content.tmpl": `{% define "content" %}{% template "card" . %}{% end %}{% define "card" %}card{% end %}`,
+		"blocks/parts.tmpl":   `{% define "header" %}h{% end %}{% define "footer" %}f{% end %}{% define "sidebar" %}{% template "widget" %}{% end %}`,
+		"blocks/widget.tmpl":  `{% define "widget" %}w{% end %}{% define "old" %}o{% end %}`,
+	})
+	blocks := filepath.Join(dir, "blocks", "*.tmpl")
+	pages := []*Template{
+		Parse(filepath.Join(dir, "home.tmpl")).Blocks(blocks),
+		Parse(filepath.Join(dir, "about.tmpl")).Blocks(blocks),
+	}
+
+	//widget is only invoked by the unused sidebar, which still counts
+	unused, err := UnusedDefines(pages, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"old", "sidebar"}; !reflect.DeepEqual(unused, want) {
+		t.Errorf("got %q, want %q", unused, want)
+	}
+
+	//with only one page the defines only the other one invokes are unused,
+	//while card is still invoked by a block
+	unused, err = UnusedDefines(pages[1:], blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"content", "footer", "old", "sidebar"}; !reflect.DeepEqual(unused, want) {
+		t.Errorf("one page: got %q, want %q", unused, want)
+	}
+}
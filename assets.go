@@ -0,0 +1,80 @@
+package tmplmgr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+//WithAssets attaches an asset function to the template that turns the path of
+//a file under root into a cache busting URL, so {% asset "css/app.css" %}
+//renders as /css/app.css?v=<hash of the file>. In Production mode each hash is
+//computed once; in Development mode, of the package or set with SetMode, it is
+//recomputed when the file changes.
+func (t *Template) WithAssets(root string) *Template {
+	a := &assetHasher{root: root, mode: t.compileMode, hashes: map[string]assetHash{}}
+	return t.Call("asset", a.asset)
+}
+
+//assetHasher computes and caches the content hashes of files under root.
+type assetHasher struct {
+	root string
+	mode func() Mode //mode of the template, called while it executes
+
+	mu     sync.Mutex
+	hashes map[string]assetHash
+}
+
+//assetHash is the hash of a file along with what it was computed from.
+type assetHash struct {
+	mod  time.Time
+	size int64
+	sum  string
+}
+
+//asset returns the fingerprinted URL for the named file.
+func (a *assetHasher) asset(name string) (url string, err error) {
+	url = path.Clean("/" + name)
+	file := filepath.Join(a.root, filepath.FromSlash(url))
+
+	a.mu.Lock()
+	cached, ex := a.hashes[url]
+	a.mu.Unlock()
+	if ex && a.mode() == Production {
+		url += "?v=" + cached.sum
+		return
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return
+	}
+	if ex && info.ModTime().Equal(cached.mod) && info.Size() == cached.size {
+		url += "?v=" + cached.sum
+		return
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return
+	}
+	sum := hex.EncodeToString(h.Sum(nil))[:12]
+
+	a.mu.Lock()
+	a.hashes[url] = assetHash{mod: info.ModTime(), size: info.Size(), sum: sum}
+	a.mu.Unlock()
+
+	url += "?v=" + sum
+	return
+}
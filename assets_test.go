@@ -0,0 +1,36 @@
+package tmplmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAssetsTemplateMode(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl":          `{% asset "css/app.css" %}`,
+		"static/css/app.css": `a`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).WithAssets(filepath.Join(dir, "static"))
+	first, err := tm.ExecuteString(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	css := filepath.Join(dir, "static", "css", "app.css")
+	writeFiles(t, dir, map[string]string{"static/css/app.css": `b`})
+	future := time.Now().Add(time.Second)
+	if err := os.Chtimes(css, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := tm.ExecuteString(nil); out != first {
+		t.Fatalf("Production recomputed the hash: %q, was %q", out, first)
+	}
+
+	tm.SetMode(Development)
+	if out, _ := tm.ExecuteString(nil); out == first {
+		t.Fatalf("Development kept the hash %q", out)
+	}
+}
@@ -0,0 +1,70 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+)
+
+//RenderResult is the output of a render delivered by ExecuteAsync or
+//ExecuteBatchAsync. Index is the position of the context in the batch, and 0
+//for ExecuteAsync.
+type RenderResult struct {
+	Index int
+	Bytes []byte
+	Err   error
+}
+
+//ExecuteAsync renders the template in a goroutine of its own, delivering the
+//output on the returned channel, which is closed after. The channel is
+//buffered, so the goroutine finishes even if nothing reads the result.
+func (t *Template) ExecuteAsync(ctx interface{}, globs ...string) <-chan RenderResult {
+	out := make(chan RenderResult, 1)
+	go func() {
+		defer close(out)
+		out <- t.renderResult(0, ctx, globs)
+	}()
+	return out
+}
+
+//ExecuteBatchAsync renders the template once for every context, up to
+//GOMAXPROCS at a time, delivering every output on the returned channel as it
+//completes, in no particular order, and closing it after the last. The channel
+//holds all the results, so the renders finish even if nothing reads them.
+func (t *Template) ExecuteBatchAsync(contexts []interface{}, globs ...string) <-chan RenderResult {
+	out := make(chan RenderResult, len(contexts))
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(contexts) {
+		workers = len(contexts)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out <- t.renderResult(i, contexts[i], globs)
+			}
+		}()
+	}
+	go func() {
+		for i := range contexts {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+//renderResult executes the template into a RenderResult.
+func (t *Template) renderResult(i int, ctx interface{}, globs []string) RenderResult {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, ctx, globs...); err != nil {
+		return RenderResult{Index: i, Err: err}
+	}
+	return RenderResult{Index: i, Bytes: buf.Bytes()}
+}
@@ -0,0 +1,80 @@
+package tmplmgr
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+var errOdd = errors.New("odd")
+
+//asyncTemplate returns a template rendering its int context, failing for odd
+//ones.
+func asyncTemplate(t *testing.T) *Template {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl":  `<p>{% check . %}{% block "b" . %}{% end %}</p>`,
+		"block.tmpl": `{% define "b" %}-{% . %}{% end %}`,
+	})
+	return Parse(filepath.Join(dir, "base.tmpl")).Call("check", func(i int) (int, error) {
+		if i%2 == 1 {
+			return 0, errOdd
+		}
+		return i, nil
+	})
+}
+
+func TestExecuteAsync(t *testing.T) {
+	tm := asyncTemplate(t)
+	res, ok := <-tm.ExecuteAsync(2)
+	if !ok || res.Err != nil || string(res.Bytes) != "<p>2</p>" || res.Index != 0 {
+		t.Errorf("got %+v", res)
+	}
+	res = <-tm.ExecuteAsync(1)
+	if !errors.Is(res.Err, errOdd) || res.Bytes != nil {
+		t.Errorf("got %+v", res)
+	}
+
+	//the channel is buffered and closed after the result
+	ch := tm.ExecuteAsync(4)
+	<-ch
+	if _, ok := <-ch; ok {
+		t.Error("channel not closed")
+	}
+}
+
+func TestExecuteBatchAsync(t *testing.T) {
+	tm := asyncTemplate(t)
+	glob := filepath.Join(filepath.Dir(tm.base), "block.tmpl")
+	contexts := make([]interface{}, 50)
+	for i := range contexts {
+		contexts[i] = i
+	}
+
+	results := map[int]RenderResult{}
+	for res := range tm.ExecuteBatchAsync(contexts, glob) {
+		if _, ok := results[res.Index]; ok {
+			t.Fatalf("index %d delivered twice", res.Index)
+		}
+		results[res.Index] = res
+	}
+	if len(results) != len(contexts) {
+		t.Fatalf("got %d results", len(results))
+	}
+	for i, res := range results {
+		if i%2 == 1 {
+			if !errors.Is(res.Err, errOdd) {
+				t.Errorf("%d: got %+v", i, res)
+			}
+			continue
+		}
+		if want := "<p>" + strconv.Itoa(i) + "-" + strconv.Itoa(i) + "</p>"; res.Err != nil || string(res.Bytes) != want {
+			t.Errorf("%d: got %q, %v, want %q", i, res.Bytes, res.Err, want)
+		}
+	}
+
+	if _, ok := <-tm.ExecuteBatchAsync(nil); ok {
+		t.Error("empty batch delivered a result")
+	}
+}
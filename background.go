@@ -0,0 +1,101 @@
+package tmplmgr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+//BackgroundCompile sets if the template recompiles in the background in
+//Development mode. Instead of compiling before every Execute, Execute serves
+//the current compiled template right away and checks in the background if any
+//of its files changed, swapping in a freshly compiled template when one did.
+//The first Execute after a change may serve the old output. Changes attached
+//through the template's methods still compile before the next Execute.
+func (t *Template) BackgroundCompile(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.background = on
+	if t.exec_globs == nil {
+		t.exec_globs = map[string][]string{}
+	}
+	t.dirty = true
+	return t
+}
+
+//watching reports if the template checks for changes in the background. The
+//caller must hold at least the read lock.
+func (t *Template) watching() bool {
+	return t.background && t.compileMode() == Development && !t.immutable
+}
+
+//checkChanges starts a goroutine that recompiles the template if its files
+//changed since the last compile, unless one is already running.
+func (t *Template) checkChanges() {
+	if !atomic.CompareAndSwapInt32(&t.checking, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&t.checking, 0)
+
+		t.compile_lock.RLock()
+		changed := t.fileStamp() != t.stamp
+		t.compile_lock.RUnlock()
+		if !changed {
+			return
+		}
+
+		t.compile_lock.Lock()
+		defer t.compile_lock.Unlock()
+		if err := t.compile(context.Background()); err != nil {
+			logf("background compile of %s: %v", t.base, err)
+		}
+	}()
+}
+
+//fileStamp describes the path, modification time and size of every file the
+//template and the globs it has been executed with read, and of the lazy files,
+//so any change to them changes the stamp. Symlinks are described by the file
+//they resolve to, so pointing one at another file is a change even if both
+//look the same. The caller must hold at least the read lock.
+func (t *Template) fileStamp() string {
+	t.cache_lock.Lock()
+	keys := make([]string, 0, len(t.exec_globs))
+	for key := range t.exec_globs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var globs []string
+	for _, key := range keys {
+		globs = append(globs, t.exec_globs[key]...)
+	}
+	t.cache_lock.Unlock()
+
+	files, err := t.matchFiles(globs)
+	if err != nil {
+		return err.Error()
+	}
+	if t.fallback != "" {
+		files = append(files, t.fallback)
+	}
+	lazy, err := t.lazyFiles()
+	if err != nil {
+		return err.Error()
+	}
+	files = append(files, lazy...)
+
+	var stamp strings.Builder
+	for _, file := range files {
+		info, err := t.stat(file)
+		if err != nil {
+			fmt.Fprintf(&stamp, "%s missing\n", file)
+			continue
+		}
+		fmt.Fprintf(&stamp, "%s %s %d %d\n", file, t.resolve(file), info.ModTime().UnixNano(), info.Size())
+	}
+	return stamp.String()
+}
@@ -0,0 +1,126 @@
+package tmplmgr
+
+import (
+	"html/template"
+	"strings"
+	"sync"
+)
+
+//Cache stores the compiled template sets of a Template keyed by the globs
+//attached to them. Implementations must be safe for concurrent use and may
+//evict entries at any time; an evicted set is simply compiled again.
+type Cache interface {
+	Get(key string) (*template.Template, bool)
+	Set(key string, tmpl *template.Template)
+	Clear()
+}
+
+//RangeCache is implemented by caches that can list the sets they hold, which
+//CacheMemoryEstimate needs to count them. Range calls fn for every set and
+//fn must not modify the cache.
+type RangeCache interface {
+	Cache
+	Range(fn func(key string, tmpl *template.Template))
+}
+
+//globsKey returns the cache key for the set with the globs attached. The globs
+//are separated by a NUL byte, which can't appear in a path, so a comma in a
+//glob doesn't make two different lists of globs share a key.
+func globsKey(globs []string) string {
+	return strings.Join(globs, "\x00")
+}
+
+//MapCache is the default Cache, keeping every set in a map forever.
+type MapCache struct {
+	mu   sync.RWMutex
+	sets map[string]*template.Template
+}
+
+//NewMapCache returns an empty MapCache.
+func NewMapCache() *MapCache {
+	return &MapCache{sets: map[string]*template.Template{}}
+}
+
+//Get implements Cache.
+func (c *MapCache) Get(key string) (tmpl *template.Template, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tmpl, ok = c.sets[key]
+	return
+}
+
+//Set implements Cache.
+func (c *MapCache) Set(key string, tmpl *template.Template) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sets[key] = tmpl
+}
+
+//Clear implements Cache.
+func (c *MapCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sets = map[string]*template.Template{}
+}
+
+//Range implements RangeCache.
+func (c *MapCache) Range(fn func(key string, tmpl *template.Template)) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for key, tmpl := range c.sets {
+		fn(key, tmpl)
+	}
+}
+
+//CacheMemoryEstimate returns a rough estimate in bytes of the memory held by
+//the compiled template and the cached glob sets, summing the length of their
+//parse trees printed back out as template text. It is only meant to give the
+//order of magnitude. Sets in caches that don't implement RangeCache are not
+//counted. Executes wait while it runs.
+func (t *Template) CacheMemoryEstimate() (n int64) {
+	//executing sets may still be escaping their trees, so wait for them
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	n = treeSize(t.t)
+	if rc, ok := t.cache.(RangeCache); ok {
+		rc.Range(func(_ string, tmpl *template.Template) {
+			n += treeSize(tmpl)
+		})
+	}
+
+	t.cache_lock.Lock()
+	defer t.cache_lock.Unlock()
+	for _, tmpl := range t.sources {
+		n += treeSize(tmpl)
+	}
+	return
+}
+
+//treeSize returns the length of the text of every tree in the set.
+func treeSize(tmpl *template.Template) (n int64) {
+	if tmpl == nil {
+		return
+	}
+	for _, x := range tmpl.Templates() {
+		if x.Tree != nil && x.Tree.Root != nil {
+			n += int64(len(x.Tree.Root.String()))
+		}
+	}
+	return
+}
+
+//SetCache replaces the cache the template stores its compiled glob sets in,
+//for example with one that bounds its size. The new cache is cleared.
+func (t *Template) SetCache(c Cache) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	c.Clear()
+	t.cache = c
+	return t
+}
@@ -0,0 +1,57 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobsKeyComma(t *testing.T) {
+	dir := t.TempDir()
+	x, y := filepath.Join(dir, "x"), filepath.Join(dir, "y")
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% block "b" . %}{% end %}`,
+		"x":         `{% define "b" %}X{% end %}`,
+		"y":         `{% define "b" %}Y{% end %}`,
+		//the single glob "<dir>/x,<dir>/y" matches this file
+		"x," + dir + "/y": `{% define "b" %}comma{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+
+	if out, err := tm.ExecuteString(nil, x, y); err != nil || out != "Y" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if out, err := tm.ExecuteString(nil, x+","+y); err != nil || out != "comma" {
+		t.Fatalf("glob with a comma: got %q, %v", out, err)
+	}
+	if globsKey([]string{x, y}) == globsKey([]string{x + "," + y}) {
+		t.Error("globs share a key")
+	}
+}
+
+func TestCacheMemoryEstimate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `<p>{% block "b" . %}default{% end %}</p>`,
+		"b.tmpl":    `{% define "b" %}a longer block definition{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	if n := tm.CacheMemoryEstimate(); n != 0 {
+		t.Errorf("before compile: %d", n)
+	}
+
+	if _, err := tm.ExecuteString(nil); err != nil {
+		t.Fatal(err)
+	}
+	base := tm.CacheMemoryEstimate()
+	//the compiled set and its executed clone cached for no globs
+	if want := int64(len(`<p>{{template "b" .}}</p>default`)); base < want {
+		t.Errorf("compiled: got %d, want at least %d", base, want)
+	}
+
+	if _, err := tm.ExecuteString(nil, filepath.Join(dir, "b.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if n := tm.CacheMemoryEstimate(); n <= base+int64(len("a longer block definition")) {
+		t.Errorf("cached set not counted: %d, compiled alone %d", n, base)
+	}
+}
@@ -0,0 +1,79 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+)
+
+//output is where the package logs to while CaptureOutput runs, or nil for the
+//standard logger.
+var output struct {
+	sync.Mutex
+	w io.Writer
+}
+
+//capturing serializes CaptureOutput calls.
+var capturing sync.Mutex
+
+//logf logs the message through the standard logger, or into the buffer of the
+//running CaptureOutput.
+func logf(format string, args ...interface{}) {
+	output.Lock()
+	defer output.Unlock()
+
+	if output.w == nil {
+		log.Printf(format, args...)
+		return
+	}
+	log.New(output.w, "", 0).Printf(format, args...)
+}
+
+//CaptureOutput runs fn and returns everything the package logged while it ran,
+//one message per line, instead of sending it to the standard logger. It is
+//meant for asserting on the log in tests. Messages logged by other goroutines
+//while fn runs are captured as well, and CaptureOutput calls run one at a time.
+func CaptureOutput(fn func()) string {
+	capturing.Lock()
+	defer capturing.Unlock()
+
+	var buf bytes.Buffer
+	output.Lock()
+	output.w = &buf
+	output.Unlock()
+
+	defer func() {
+		output.Lock()
+		output.w = nil
+		output.Unlock()
+	}()
+	fn()
+
+	output.Lock()
+	defer output.Unlock()
+	return buf.String()
+}
+
+//SetTestWriter sets the writer ExecuteTest renders to. By default its output is
+//discarded.
+func (t *Template) SetTestWriter(w io.Writer) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.test_writer = w
+	return t
+}
+
+//ExecuteTest is like Execute but renders to the writer set with SetTestWriter,
+//so tests can configure where the output goes once.
+func (t *Template) ExecuteTest(ctx interface{}, globs ...string) error {
+	t.compile_lock.RLock()
+	w := t.test_writer
+	t.compile_lock.RUnlock()
+
+	if w == nil {
+		w = io.Discard
+	}
+	return t.Execute(w, ctx, globs...)
+}
@@ -0,0 +1,188 @@
+package tmplmgr
+
+import (
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+//SourceComments sets if templates compiled in Development mode wrap the output
+//of every template they invoke in <!-- begin file --> and <!-- end -->
+//comments naming the file it was defined in, to help find where generated
+//HTML came from. Only invocations in the text of the page get them, as in a
+//script, a style or an attribute the comment would be escaped into the output.
+//Templates compiled in Production mode never include them so file paths are
+//not leaked.
+func (t *Template) SourceComments(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.source_comments = on
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//sourceCommentFunc is the name of the function the comment actions call.
+const sourceCommentFunc = "_source_comment"
+
+//sourceComment returns the comment as HTML so the escaper emits it as is;
+//comments written in the template text itself are stripped.
+func sourceComment(text string) template.HTML {
+	return template.HTML("<!-- " + text + " -->")
+}
+
+//sourceComments wraps every template action in the set with comment actions.
+//Actions that were already wrapped, in a set cloned from a wrapped one, only
+//have their begin comment updated. The caller must hold at least the read
+//lock.
+func (t *Template) sourceComments(tmpl *template.Template, globs []string) (err error) {
+	paths, err := t.filePaths(globs)
+	if err != nil {
+		return
+	}
+	tmpl.Funcs(template.FuncMap{sourceCommentFunc: sourceComment})
+
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil {
+			continue
+		}
+		walk(x.Tree.Root, func(node parse.Node) {
+			list, ok := node.(*parse.ListNode)
+			if !ok {
+				return
+			}
+
+			var nodes []parse.Node
+			for i, n := range list.Nodes {
+				tn, ok := n.(*parse.TemplateNode)
+				if !ok {
+					nodes = append(nodes, n)
+					continue
+				}
+
+				file := tn.Name
+				if def := tmpl.Lookup(tn.Name); def != nil && def.Tree != nil {
+					if path, ok := paths[def.Tree.ParseName]; ok {
+						file = path
+					}
+				}
+
+				//the globs may define the template differently, so
+				//only the begin comment is replaced
+				if i > 0 && isBeginComment(list.Nodes[i-1]) {
+					nodes[len(nodes)-1] = commentAction("begin " + file)
+					nodes = append(nodes, n)
+					continue
+				}
+				nodes = append(nodes, commentAction("begin "+file), n, commentAction("end"))
+			}
+			list.Nodes = nodes
+		})
+	}
+	dropContextComments(tmpl)
+	return
+}
+
+//dropContextComments removes the comments around the invocations that are not
+//in the text of the page, where the escaper would escape them as a string for
+//a script or an attribute. The contexts are found by escaping a copy of the
+//set: executing it escapes every template before running it, and the run soon
+//stops at the first function call, as the copy has no functions. Comments
+//whose context can't be told are removed too.
+func dropContextComments(tmpl *template.Template) {
+	scratch := template.New(tmpl.Name())
+	var names []string
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil {
+			continue
+		}
+		if _, err := scratch.AddParseTree(x.Name(), x.Tree.Copy()); err != nil {
+			return
+		}
+		names = append(names, x.Name())
+	}
+	for _, name := range names {
+		scratch.ExecuteTemplate(io.Discard, name, nil)
+	}
+
+	inText := map[parse.Node]bool{}
+	for _, name := range names {
+		orig, escaped := commentNodes(tmpl.Lookup(name)), commentNodes(scratch.Lookup(name))
+		if len(orig) != len(escaped) {
+			continue
+		}
+		for i, n := range escaped {
+			cmds := n.Pipe.Cmds
+			if len(cmds) != 2 || len(cmds[1].Args) != 1 {
+				continue
+			}
+			if id, ok := cmds[1].Args[0].(*parse.IdentifierNode); ok && id.Ident == "_html_template_htmlescaper" {
+				inText[orig[i]] = true
+			}
+		}
+	}
+
+	for _, name := range names {
+		walk(tmpl.Lookup(name).Tree.Root, func(node parse.Node) {
+			list, ok := node.(*parse.ListNode)
+			if !ok {
+				return
+			}
+			var nodes []parse.Node
+			for _, n := range list.Nodes {
+				if isSourceComment(n) && !inText[n] {
+					continue
+				}
+				nodes = append(nodes, n)
+			}
+			list.Nodes = nodes
+		})
+	}
+}
+
+//commentNodes returns the comment actions of the template in document order.
+func commentNodes(x *template.Template) (nodes []*parse.ActionNode) {
+	if x == nil || x.Tree == nil {
+		return
+	}
+	walk(x.Tree.Root, func(node parse.Node) {
+		if isSourceComment(node) {
+			nodes = append(nodes, node.(*parse.ActionNode))
+		}
+	})
+	return
+}
+
+//commentAction returns an action node emitting the comment text.
+func commentAction(text string) parse.Node {
+	src := `{{` + sourceCommentFunc + ` ` + strconv.Quote(text) + `}}`
+	trees, err := parse.Parse("source", src, `{{`, `}}`, map[string]interface{}{sourceCommentFunc: sourceComment})
+	if err != nil {
+		panic(err)
+	}
+	return trees["source"].Root.Nodes[0]
+}
+
+//isSourceComment reports if the node is an action made by commentAction,
+//including after the escaper added its commands to it.
+func isSourceComment(node parse.Node) bool {
+	a, ok := node.(*parse.ActionNode)
+	if !ok || len(a.Pipe.Cmds) == 0 || len(a.Pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+	id, ok := a.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && id.Ident == sourceCommentFunc
+}
+
+//isBeginComment reports if the node is a begin comment made by commentAction.
+func isBeginComment(node parse.Node) bool {
+	if !isSourceComment(node) {
+		return false
+	}
+	args := node.(*parse.ActionNode).Pipe.Cmds[0].Args
+	s, ok := args[len(args)-1].(*parse.StringNode)
+	return ok && strings.HasPrefix(s.Text, "begin ")
+}
@@ -0,0 +1,29 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSourceCommentsTextOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `<p>{% template "name" . %}</p><script>var n = {% template "name" . %};</script><a title="{% template "name" . %}">x</a>`,
+		"name.tmpl": `{% define "name" %}{% .Name %}{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Blocks(filepath.Join(dir, "name.tmpl")).SourceComments(true)
+	tm.SetMode(Development)
+
+	out, err := tm.ExecuteString(map[string]string{"Name": "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	begin := "<!-- begin " + filepath.Join(dir, "name.tmpl") + " -->"
+	if !strings.HasPrefix(out, "<p>"+begin+"n<!-- end --></p>") {
+		t.Errorf("text invocation got no comment: %q", out)
+	}
+	if strings.Count(out, "begin") != 1 || !strings.Contains(out, `var n = "n";`) || !strings.Contains(out, `title="n"`) {
+		t.Errorf("comments outside the text: %q", out)
+	}
+}
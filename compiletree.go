@@ -0,0 +1,73 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Errors holds every error an operation on many templates ran into.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+//CompileTree parses every file matching the pages glob as the base of a
+//Template of its own with the blocks attached, and compiles them concurrently
+//with up to workers at a time, or GOMAXPROCS if workers is not positive. It
+//returns the templates keyed by their file. Pages that fail to compile are
+//left out, and their errors are returned together as Errors, sorted by file.
+func CompileTree(pages string, workers int, blocks ...string) (map[string]*Template, error) {
+	files, err := filepath.Glob(pages)
+	if err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		mu   sync.Mutex
+		tree = map[string]*Template{}
+		errs Errors
+	)
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				t := Parse(file).Blocks(blocks...)
+				err := t.Compile()
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %v", file, err))
+				} else {
+					tree[file] = t
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, file := range files {
+		jobs <- file
+	}
+	close(jobs)
+	wg.Wait()
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return tree, errs
+	}
+	return tree, nil
+}
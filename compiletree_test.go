@@ -0,0 +1,40 @@
+package tmplmgr
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompileTreeErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"pages/a.tmpl":  `<p>{% block "b" . %}{% end %}</p>`,
+		"pages/b.tmpl":  `{% if %}`,
+		"pages/c.tmpl":  `<p>{% template "b" . %}</p>`,
+		"pages/d.tmpl":  `{% .Name`,
+		"blocks/b.tmpl": `{% define "b" %}b{% end %}`,
+	})
+	tree, err := CompileTree(filepath.Join(dir, "pages", "*.tmpl"), 2, filepath.Join(dir, "blocks", "*.tmpl"))
+
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("got %v", err)
+	}
+	for i, name := range []string{"b.tmpl", "d.tmpl"} {
+		if !strings.HasPrefix(errs[i].Error(), filepath.Join(dir, "pages", name)+": ") {
+			t.Errorf("error %d is %q, not for %s", i, errs[i], name)
+		}
+	}
+	if !strings.Contains(err.Error(), "; ") {
+		t.Errorf("errors not joined: %q", err)
+	}
+
+	if len(tree) != 2 {
+		t.Fatalf("got %d templates", len(tree))
+	}
+	if out, err := tree[filepath.Join(dir, "pages", "c.tmpl")].ExecuteString(nil); err != nil || out != "<p>b</p>" {
+		t.Errorf("got %q, %v", out, err)
+	}
+}
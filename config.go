@@ -0,0 +1,92 @@
+package tmplmgr
+
+import (
+	"sort"
+	"time"
+)
+
+//TemplateConfig describes how a Template is configured, as returned by
+//Config.
+type TemplateConfig struct {
+	Base         string   //base file
+	Fallback     string   //base file used when Base does not exist
+	Blocks       []string //globs attached with Blocks
+	GroupBlocks  []string //globs attached with BlocksWith
+	Files        []string //files attached with Files
+	LazyBlocks   []string //globs attached with LazyBlocks
+	DefaultGlobs []string //globs parsed in before the ones passed to Execute
+	Funcs        []string //sorted names of the funcs attached with Call and friends
+
+	Left, Right string //action delimiters
+
+	Mode        Mode //mode of the template, see SetMode
+	ModeSet     bool //whether SetMode overrides the package's mode
+	Development bool //whether the template compiles on every Execute
+	Background  bool //whether it recompiles in the background on changes
+	Immutable   bool //whether its files are treated as never changing
+
+	Requires        []string      //context fields required by Execute
+	RequireGlobs    []string      //globs required by Execute
+	RequireNonEmpty bool          //whether an empty base fails the compile
+	Trim            bool          //whether whitespace in text is collapsed
+	SourceComments  bool          //whether partials are marked in Development mode
+	NonceTags       bool          //whether nonce attributes are added to tags
+	MaxOutput       int64         //maximum bytes written by an Execute, or 0
+	MaxDepth        int           //maximum nesting of invocations, or 0
+	MaxContextDepth int           //maximum nesting of the context, or 0
+	CompileTimeout  time.Duration //maximum time of a compile, or 0
+	RemoteTTL       time.Duration //how long a remote compile is used, or 0
+	ContentType     string        //content type of the output
+	AsBlock         string        //name the base is also defined under
+}
+
+//Config returns the configuration of the template, to check it is set up as
+//intended, such as on a debugging endpoint.
+func (t *Template) Config() TemplateConfig {
+	contentType := t.ContentType()
+
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	c := TemplateConfig{
+		Base:         t.base,
+		Fallback:     t.fallback,
+		Blocks:       append([]string(nil), t.blocks...),
+		Files:        append([]string(nil), t.files...),
+		LazyBlocks:   append([]string(nil), t.lazy...),
+		DefaultGlobs: append([]string(nil), t.default_globs...),
+
+		Mode:        t.compileMode(),
+		ModeSet:     t.mode_set,
+		Development: t.development(),
+		Background:  t.background,
+		Immutable:   t.immutable,
+
+		Requires:        append([]string(nil), t.requires...),
+		RequireGlobs:    append([]string(nil), t.require_globs...),
+		RequireNonEmpty: t.require_nonempty,
+		Trim:            t.trim,
+		SourceComments:  t.source_comments,
+		NonceTags:       t.nonce_tags,
+		MaxOutput:       t.max_output,
+		MaxDepth:        t.max_depth,
+		MaxContextDepth: t.max_ctx_depth,
+		CompileTimeout:  t.compile_timeout,
+		RemoteTTL:       t.remote_ttl,
+		ContentType:     contentType,
+		AsBlock:         t.as_block,
+	}
+	c.Left, c.Right = t.delims()
+
+	for name := range t.funcs {
+		c.Funcs = append(c.Funcs, name)
+	}
+	for _, g := range t.groups {
+		c.GroupBlocks = append(c.GroupBlocks, g.globs...)
+		for name := range g.funcs {
+			c.Funcs = append(c.Funcs, name)
+		}
+	}
+	sort.Strings(c.Funcs)
+	return c
+}
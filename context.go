@@ -0,0 +1,173 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"reflect"
+)
+
+//DefaultContext sets values that are merged beneath the context of every
+//Execute, so site wide constants don't have to be added by every caller. The
+//merge only happens for nil contexts and maps keyed by strings, where keys in
+//the context passed to Execute override the defaults. Other contexts such as
+//structs are passed through unchanged; embed the values in them instead.
+func (t *Template) DefaultContext(defaults map[string]interface{}) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.defaults = make(map[string]interface{}, len(defaults))
+	for k, v := range defaults {
+		t.defaults[k] = v
+	}
+	return t
+}
+
+//withDefaults returns ctx with the default context merged beneath it. The
+//caller must hold at least the read lock.
+func (t *Template) withDefaults(ctx interface{}) interface{} {
+	if len(t.defaults) == 0 {
+		return ctx
+	}
+	if ctx == nil {
+		return t.defaults
+	}
+
+	v := reflect.ValueOf(ctx)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return ctx
+	}
+
+	merged := make(map[string]interface{}, len(t.defaults)+v.Len())
+	for k, val := range t.defaults {
+		merged[k] = val
+	}
+	for iter := v.MapRange(); iter.Next(); {
+		merged[iter.Key().String()] = iter.Value().Interface()
+	}
+	return merged
+}
+
+//MaxContextDepth makes every Execute walk its context first, failing with an
+//error if the context contains a cycle or nests maps, slices, arrays and
+//structs more than n levels deep, to guard against pathological user supplied
+//data. Pointers and interfaces do not count as levels, and only the exported
+//fields of structs are walked, as those are all the template can reach. The
+//walk costs time proportional to the size of the context, so zero, the
+//default, turns it off.
+func (t *Template) MaxContextDepth(n int) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.max_ctx_depth = n
+	return t
+}
+
+//checkContextDepth returns an error if ctx has a cycle or is nested deeper than
+//the maximum context depth. The caller must hold at least the read lock.
+func (t *Template) checkContextDepth(ctx interface{}) error {
+	if t.max_ctx_depth <= 0 {
+		return nil
+	}
+	w := &contextWalker{
+		max:     t.max_ctx_depth,
+		path:    map[walkKey]bool{},
+		heights: map[walkKey]int{},
+	}
+	_, err := w.walk(reflect.ValueOf(ctx), 0)
+	return err
+}
+
+//walkKey identifies a map, slice or pointer by what it points to, and a slice
+//also by its length, as slices of one array can hold different elements.
+type walkKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+//contextWalker walks a context, tracking the values on the path to the current
+//one to find cycles, and the heights of the values already walked so values
+//shared by several parents are only walked once.
+type contextWalker struct {
+	max     int
+	path    map[walkKey]bool
+	heights map[walkKey]int
+}
+
+//walk returns the height of v, found at the depth, or an error if it is part
+//of a cycle or too deep.
+func (w *contextWalker) walk(v reflect.Value, depth int) (height int, err error) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		return w.walk(v.Elem(), depth)
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		key := walkKey{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if w.path[key] {
+			return 0, fmt.Errorf("context contains a cycle through %s", v.Type())
+		}
+		if h, ok := w.heights[key]; ok {
+			if depth+h > w.max {
+				return 0, fmt.Errorf("context nests deeper than %d levels", w.max)
+			}
+			return h, nil
+		}
+
+		w.path[key] = true
+		defer delete(w.path, key)
+		if v.Kind() == reflect.Ptr {
+			height, err = w.walk(v.Elem(), depth)
+		} else {
+			height, err = w.children(v, depth)
+		}
+		if err == nil {
+			w.heights[key] = height
+		}
+		return
+	case reflect.Array, reflect.Struct:
+		return w.children(v, depth)
+	}
+	return
+}
+
+//children walks the elements of the map, slice or array or the exported fields
+//of the struct v, found at the depth, returning the height of v.
+func (w *contextWalker) children(v reflect.Value, depth int) (height int, err error) {
+	if depth++; depth > w.max {
+		return 0, fmt.Errorf("context nests deeper than %d levels", w.max)
+	}
+
+	var h int
+	visit := func(c reflect.Value) {
+		if err != nil {
+			return
+		}
+		if h, err = w.walk(c, depth); h > height {
+			height = h
+		}
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		for iter := v.MapRange(); iter.Next(); {
+			visit(iter.Value())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			visit(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				visit(v.Field(i))
+			}
+		}
+	}
+	return height + 1, err
+}
@@ -0,0 +1,25 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxContextDepthSubslice(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `ok`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).MaxContextDepth(3)
+
+	//the struct nests s[1] four levels deep, the prefix s[:1] only two
+	s := []interface{}{1, []interface{}{[]int{1}}}
+	type pair struct{ A, B []interface{} }
+	for _, ctx := range []interface{}{pair{B: s}, pair{A: s[:1], B: s}} {
+		if _, err := tm.ExecuteString(ctx); err == nil || !strings.Contains(err.Error(), "deeper than 3") {
+			t.Errorf("%v: got %v, want too deep", ctx, err)
+		}
+	}
+	if out, err := tm.ExecuteString(pair{A: s[:1], B: s[:1]}); err != nil || out != "ok" {
+		t.Errorf("got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,54 @@
+package tmplmgr
+
+import (
+	"fmt"
+)
+
+//Style is a named pair of delimiters for DelimStyle.
+type Style int
+
+const (
+	StyleGoods Style = iota //{% and %}, the default
+	StyleGo                 //{{ and }}, as in the standard library
+	StyleERB                //<% and %>
+)
+
+var styles = map[Style][2]string{
+	StyleGoods: {`{%`, `%}`},
+	StyleGo:    {`{{`, `}}`},
+	StyleERB:   {`<%`, `%>`},
+}
+
+//Delims sets the action delimiters the base, Blocks and the globs passed to
+//Execute are parsed with. Empty delimiters stand for the defaults of {% and %}.
+func (t *Template) Delims(left, right string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.left, t.right = left, right
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//DelimStyle sets the action delimiters to one of the predefined styles. It
+//panics if the style is unknown.
+func (t *Template) DelimStyle(style Style) *Template {
+	d, ok := styles[style]
+	if !ok {
+		panic(fmt.Sprintf("tmplmgr: unknown delimiter style %d", style))
+	}
+	return t.Delims(d[0], d[1])
+}
+
+//delims returns the delimiters the template is parsed with.
+func (t *Template) delims() (left, right string) {
+	left, right = t.left, t.right
+	if left == "" {
+		left = styles[StyleGoods][0]
+	}
+	if right == "" {
+		right = styles[StyleGoods][1]
+	}
+	return
+}
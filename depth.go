@@ -0,0 +1,145 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"text/template/parse"
+)
+
+//MaxDepth limits how deeply template invocations may nest while executing, so
+//runaway recursion or excessive nesting fails with an error instead of growing
+//the stack. The limit is counted per Execute, which gives every Execute a set
+//of its own, so it costs about as much as ExecuteNonce. That cost is why the
+//default is zero rather than a generous limit: it leaves only the limit of
+//html/template itself, which still fails runaway recursion after 100000
+//nested invocations, and keeps Executes served from the cached sets.
+func (t *Template) MaxDepth(n int) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.max_depth = n
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//TraceHook calls hook with the name of every template and block invoked while
+//executing, and the function it returns, if not nil, once the invocation
+//finished, so spans or timings can be recorded per partial. Like MaxDepth it
+//gives every Execute a set of its own, so nothing is traced and nothing is
+//paid for when no hook is set. A nil hook turns tracing off.
+func (t *Template) TraceHook(hook func(name string) func()) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.trace = hook
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//names of the functions counting the depth around every invocation.
+const (
+	depthEnterFunc = "_depth_enter"
+	depthLeaveFunc = "_depth_leave"
+)
+
+//counted reports if invocations are surrounded with the depth functions, to
+//limit their depth or to trace them. The caller must hold at least the read
+//lock.
+func (t *Template) counted() bool {
+	return t.max_depth > 0 || t.trace != nil
+}
+
+//depthFuncs returns the functions counting the depth for one Execute, adding
+//them to funcs. A max of zero does not limit the depth. If trace is not nil it
+//is called on entering every invocation, and the function it returns on
+//leaving it. The returned done function finishes the invocations left open by
+//a failed Execute and must be called once it returns.
+func depthFuncs(funcs template.FuncMap, max int, trace func(string) func()) (template.FuncMap, func()) {
+	out := template.FuncMap{}
+	for name, fnc := range funcs {
+		out[name] = fnc
+	}
+
+	depth := 0
+	var open []func()
+	out[depthEnterFunc] = func(name string) (bool, error) {
+		if depth++; max > 0 && depth > max {
+			return false, fmt.Errorf("template nesting exceeded depth %d", max)
+		}
+		if trace != nil {
+			open = append(open, trace(name))
+		}
+		return false, nil
+	}
+	out[depthLeaveFunc] = func() (bool, error) {
+		depth--
+		if n := len(open); n > 0 {
+			finish := open[n-1]
+			open = open[:n-1]
+			if finish != nil {
+				finish()
+			}
+		}
+		return false, nil
+	}
+	done := func() {
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i] != nil {
+				open[i]()
+			}
+		}
+		open = nil
+	}
+	return out, done
+}
+
+//depthCounters surrounds every template invocation in the set with calls to
+//the depth functions. They are made in the conditions of empty if actions so
+//they never write anything, whatever the context. Invocations that were
+//already surrounded, in a set cloned from a counted one, are left alone.
+func depthCounters(tmpl *template.Template) {
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil {
+			continue
+		}
+		walk(x.Tree.Root, func(node parse.Node) {
+			list, ok := node.(*parse.ListNode)
+			if !ok {
+				return
+			}
+
+			var nodes []parse.Node
+			for i, n := range list.Nodes {
+				if _, ok := n.(*parse.TemplateNode); !ok || (i > 0 && isDepthCall(list.Nodes[i-1])) {
+					nodes = append(nodes, n)
+					continue
+				}
+				call := n.(*parse.TemplateNode)
+				nodes = append(nodes, depthCall(depthEnterFunc+" "+strconv.Quote(call.Name)), n, depthCall(depthLeaveFunc))
+			}
+			list.Nodes = nodes
+		})
+	}
+}
+
+//depthCall returns an empty if action with the call in its condition.
+func depthCall(call string) parse.Node {
+	trees, err := parse.Parse("depth", `{{if `+call+`}}{{end}}`, `{{`, `}}`, requestFuncs)
+	if err != nil {
+		panic(err)
+	}
+	return trees["depth"].Root.Nodes[0]
+}
+
+//isDepthCall reports if the node is an action made by depthCall.
+func isDepthCall(node parse.Node) bool {
+	n, ok := node.(*parse.IfNode)
+	if !ok || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) == 0 {
+		return false
+	}
+	id, ok := n.Pipe.Cmds[0].Args[0].(*parse.IdentifierNode)
+	return ok && (id.Ident == depthEnterFunc || id.Ident == depthLeaveFunc)
+}
@@ -0,0 +1,214 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+//diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+//Diff executes both the template and other with the same context and globs
+//and returns a unified diff of the outputs, from the template's to other's. An
+//empty diff means the outputs are identical. Diffing takes memory linear in
+//the number of lines, while its time grows with the product of the numbers of
+//lines of each output between the first and last change.
+func (t *Template) Diff(other *Template, ctx interface{}, globs ...string) (diff string, err error) {
+	a, err := t.ExecuteString(ctx, globs...)
+	if err != nil {
+		return
+	}
+	b, err := other.ExecuteString(ctx, globs...)
+	if err != nil {
+		return
+	}
+	diff = unifiedDiff(t.base, other.base, a, b)
+	return
+}
+
+//diffOp is a single line of an edit script: kept, deleted or inserted. a and
+//b are the indexes of the line in each side.
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+//splitLines splits s into lines keeping their line endings.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+//editScript returns the shortest edit script turning a into b. Lines common
+//to the start and end are kept first, and the rest is diffed with Hirschberg's
+//algorithm, which finds the longest common subsequence in space linear in the
+//number of lines.
+func editScript(a, b []string) (ops []diffOp) {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		ops = append(ops, diffOp{' ', a[pre], pre, pre})
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops = hirschberg(ops, a[pre:len(a)-suf], b[pre:len(b)-suf], pre, pre)
+	for i := len(a) - suf; i < len(a); i++ {
+		j := i - len(a) + len(b)
+		ops = append(ops, diffOp{' ', a[i], i, j})
+	}
+	return
+}
+
+//hirschberg appends the edit script turning a into b to ops, where ai and bi
+//are the indexes of the first lines of a and b in the whole inputs.
+func hirschberg(ops []diffOp, a, b []string, ai, bi int) []diffOp {
+	switch {
+	case len(a) == 0:
+		for j, line := range b {
+			ops = append(ops, diffOp{'+', line, ai, bi + j})
+		}
+		return ops
+	case len(b) == 0:
+		for i, line := range a {
+			ops = append(ops, diffOp{'-', line, ai + i, bi})
+		}
+		return ops
+	case len(a) == 1:
+		for j, line := range b {
+			if line != a[0] {
+				continue
+			}
+			ops = hirschberg(ops, nil, b[:j], ai, bi)
+			ops = append(ops, diffOp{' ', a[0], ai, bi + j})
+			return hirschberg(ops, nil, b[j+1:], ai+1, bi+j+1)
+		}
+		ops = append(ops, diffOp{'-', a[0], ai, bi})
+		return hirschberg(ops, nil, b, ai+1, bi)
+	}
+
+	//split b where the halves of a line up best
+	mid := len(a) / 2
+	head, tail := lcsLengths(a[:mid], b, false), lcsLengths(a[mid:], b, true)
+	split, best := 0, -1
+	for j := 0; j <= len(b); j++ {
+		if n := head[j] + tail[len(b)-j]; n > best {
+			split, best = j, n
+		}
+	}
+	ops = hirschberg(ops, a[:mid], b[:split], ai, bi)
+	return hirschberg(ops, a[mid:], b[split:], ai+mid, bi+split)
+}
+
+//lcsLengths returns the length of the longest common subsequence of a and
+//every prefix of b, by length of the prefix, or of every suffix of b if
+//reverse is set, reading both a and b from their end.
+func lcsLengths(a, b []string, reverse bool) []int {
+	at := func(s []string, i int) string {
+		if reverse {
+			return s[len(s)-1-i]
+		}
+		return s[i]
+	}
+
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case at(a, i) == at(b, j):
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+//unifiedDiff returns the unified diff between a and b, or an empty string if
+//they are equal.
+func unifiedDiff(aname, bname, a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	ops := editScript(splitLines(a), splitLines(b))
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", aname, bname)
+
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		//extend the hunk over changes separated by little enough context
+		start, end := i-diffContext, i
+		if start < 0 {
+			start = 0
+		}
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*diffContext {
+				end = next
+				continue
+			}
+			if end += diffContext; end > len(ops) {
+				end = len(ops)
+			}
+			break
+		}
+
+		writeHunk(&out, ops[start:end])
+		i = end
+	}
+	return out.String()
+}
+
+//writeHunk writes the header and lines of a single hunk.
+func writeHunk(out *bytes.Buffer, ops []diffOp) {
+	acount, bcount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			acount++
+		}
+		if op.kind != '-' {
+			bcount++
+		}
+	}
+
+	astart, bstart := ops[0].a, ops[0].b
+	if acount > 0 {
+		astart++
+	}
+	if bcount > 0 {
+		bstart++
+	}
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", astart, acount, bstart, bcount)
+
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		if !strings.HasSuffix(op.line, "\n") {
+			out.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package tmplmgr
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	cases := []struct {
+		a, b, diff string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"a\nb\nc\n", "a\nB\nc\n", "--- x\n+++ y\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"", "a\n", "--- x\n+++ y\n@@ -0,0 +1,1 @@\n+a\n"},
+		{"a", "b", "--- x\n+++ y\n@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n"},
+		//changes far apart get hunks of their own
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "0\n2\n3\n4\n5\n6\n7\n8\n9\n11\n",
+			"--- x\n+++ y\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+11\n"},
+	}
+	for _, c := range cases {
+		if got := unifiedDiff("x", "y", c.a, c.b); got != c.diff {
+			t.Errorf("diff of %q and %q:\ngot  %q\nwant %q", c.a, c.b, got, c.diff)
+		}
+	}
+}
+
+func TestEditScript(t *testing.T) {
+	//lcs is the length of the longest common subsequence, the number of
+	//lines the shortest edit script keeps
+	lcs := func(a, b []string) int {
+		n := make([][]int, len(a)+1)
+		for i := range n {
+			n[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				switch {
+				case a[i] == b[j]:
+					n[i][j] = n[i+1][j+1] + 1
+				case n[i+1][j] > n[i][j+1]:
+					n[i][j] = n[i+1][j]
+				default:
+					n[i][j] = n[i][j+1]
+				}
+			}
+		}
+		return n[0][0]
+	}
+	lines := func(r *rand.Rand) []string {
+		out := make([]string, r.Intn(30))
+		for i := range out {
+			out[i] = strconv.Itoa(r.Intn(5))
+		}
+		return out
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for k := 0; k < 500; k++ {
+		a, b := lines(r), lines(r)
+		var gotA, gotB []string
+		kept := 0
+		for _, op := range editScript(a, b) {
+			if op.kind != '+' {
+				if op.a >= len(a) || a[op.a] != op.line {
+					t.Fatalf("%q to %q: bad index a %d in %+v", a, b, op.a, op)
+				}
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				if op.b >= len(b) || b[op.b] != op.line {
+					t.Fatalf("%q to %q: bad index b %d in %+v", a, b, op.b, op)
+				}
+				gotB = append(gotB, op.line)
+			}
+			if op.kind == ' ' {
+				kept++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("%q to %q: script gives %q and %q", a, b, gotA, gotB)
+		}
+		if want := lcs(a, b); kept != want {
+			t.Fatalf("%q to %q: kept %d lines, want %d", a, b, kept, want)
+		}
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	a := make([]string, 3000)
+	for i := range a {
+		a[i] = "line " + strconv.Itoa(i) + "\n"
+	}
+	b := append([]string{"first\n"}, a...)
+	b[1500] = "changed\n"
+	b = append(b, "last\n")
+
+	diff := unifiedDiff("x", "y", strings.Join(a, ""), strings.Join(b, ""))
+	want := "--- x\n+++ y\n@@ -1,3 +1,4 @@\n+first\n line 0\n line 1\n line 2\n" +
+		"@@ -1497,7 +1498,7 @@\n line 1496\n line 1497\n line 1498\n-line 1499\n+changed\n line 1500\n line 1501\n line 1502\n" +
+		"@@ -2998,3 +2999,4 @@\n line 2997\n line 2998\n line 2999\n+last\n"
+	if diff != want {
+		t.Errorf("got %q, want %q", diff, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"old.tmpl": "<h1>{% .Title %}</h1>\n<p>old</p>\n",
+		"new.tmpl": "<h1>{% .Title %}</h1>\n<p>new</p>\n",
+	})
+	old, cur := Parse(filepath.Join(dir, "old.tmpl")), Parse(filepath.Join(dir, "new.tmpl"))
+	ctx := map[string]string{"Title": "t"}
+
+	diff, err := old.Diff(cur, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "--- " + filepath.Join(dir, "old.tmpl") + "\n+++ " + filepath.Join(dir, "new.tmpl") +
+		"\n@@ -1,2 +1,2 @@\n <h1>t</h1>\n-<p>old</p>\n+<p>new</p>\n"
+	if diff != want {
+		t.Errorf("got %q, want %q", diff, want)
+	}
+	if diff, err := old.Diff(old, ctx); err != nil || diff != "" {
+		t.Errorf("same template: got %q, %v", diff, err)
+	}
+}
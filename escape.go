@@ -0,0 +1,68 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+//EscapeMode is the context ExecuteContextEscape escapes the output for.
+type EscapeMode int
+
+const (
+	EscapeHTML EscapeMode = iota //text of an HTML element or attribute value
+	EscapeJS                     //inside a JavaScript string literal
+	EscapeCSS                    //inside a CSS string or identifier
+	EscapeURL                    //a URL query component
+)
+
+//ExecuteContextEscape is like Execute but escapes the whole output for the
+//given context before writing it, so one template's rendered HTML can be
+//embedded in another's JavaScript string, CSS or URL. Since the output is
+//escaped as a whole, it is buffered and nothing is written on error.
+func (t *Template) ExecuteContextEscape(w io.Writer, esc EscapeMode, ctx interface{}, globs ...string) (err error) {
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, ctx, globs...); err != nil {
+		return
+	}
+
+	var out string
+	switch esc {
+	case EscapeHTML:
+		out = template.HTMLEscapeString(buf.String())
+	case EscapeJS:
+		out = template.JSEscapeString(buf.String())
+	case EscapeCSS:
+		out = cssEscape(buf.String())
+	case EscapeURL:
+		out = template.URLQueryEscaper(buf.String())
+	default:
+		return fmt.Errorf("unknown escape mode %d", esc)
+	}
+	_, err = io.WriteString(w, out)
+	return
+}
+
+//cssEscape escapes every ASCII rune that isn't a letter or digit with a CSS
+//hex escape, followed by a space when the next rune could be read as part of it.
+func cssEscape(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r >= 0x80 {
+			b.WriteRune(r)
+			continue
+		}
+		fmt.Fprintf(&b, "\\%x", r)
+		if i+1 < len(runes) && (isHex(runes[i+1]) || runes[i+1] == ' ' || runes[i+1] == '\t') {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+func isHex(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
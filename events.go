@@ -0,0 +1,49 @@
+package tmplmgr
+
+import (
+	"sync"
+	"time"
+)
+
+//EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	CompileEvent EventKind = iota
+	ExecuteEvent
+)
+
+//Event describes a compile or execute of a template, sent to every function
+//registered with Subscribe.
+type Event struct {
+	Kind     EventKind
+	Base     string        //base file of the template
+	Duration time.Duration //time spent compiling or executing
+	CacheHit bool          //for executes, whether the set came from the cache
+	Err      error         //error returned by the compile or execute, if any
+}
+
+var subscribers struct {
+	sync.RWMutex
+	fns []func(Event)
+}
+
+//Subscribe registers fn to be called synchronously with an Event after every
+//compile and execute of every template. It is intended for metrics, so fn
+//should return quickly.
+func Subscribe(fn func(Event)) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+
+	subscribers.fns = append(subscribers.fns, fn)
+}
+
+//emit sends the event to all of the subscribers.
+func emit(ev Event) {
+	subscribers.RLock()
+	defer subscribers.RUnlock()
+
+	for _, fn := range subscribers.fns {
+		fn(ev)
+	}
+}
@@ -0,0 +1,212 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//ExecuteAtomic is like Execute but renders into a buffer first, only writing
+//to w once the whole template has executed successfully. If there is any
+//error, nothing is written to w.
+func (t *Template) ExecuteAtomic(w io.Writer, ctx interface{}, globs ...string) (err error) {
+	buf := bytes.NewBuffer(make([]byte, 0, t.sizeHint()))
+	if err = t.Execute(buf, ctx, globs...); err != nil {
+		return
+	}
+	t.recordSize(buf.Len())
+	_, err = buf.WriteTo(w)
+	return
+}
+
+//ExecuteWrapped is like Execute but writes prefix before the output and suffix
+//after it, such as markers around a fragment. The prefix is written before
+//rendering starts, so on error w may hold the prefix and part of the output,
+//but never the suffix. Use ExecuteWrappedAtomic to write nothing on error.
+func (t *Template) ExecuteWrapped(w io.Writer, prefix, suffix []byte, ctx interface{}, globs ...string) (err error) {
+	if _, err = w.Write(prefix); err != nil {
+		return
+	}
+	if err = t.Execute(w, ctx, globs...); err != nil {
+		return
+	}
+	_, err = w.Write(suffix)
+	return
+}
+
+//ExecuteWrappedAtomic is like ExecuteWrapped but renders into a buffer first,
+//writing the prefix, output and suffix to w only once the template has
+//executed successfully.
+func (t *Template) ExecuteWrappedAtomic(w io.Writer, prefix, suffix []byte, ctx interface{}, globs ...string) (err error) {
+	buf := bytes.NewBuffer(append([]byte(nil), prefix...))
+	if err = t.Execute(buf, ctx, globs...); err != nil {
+		return
+	}
+	buf.Write(suffix)
+	_, err = buf.WriteTo(w)
+	return
+}
+
+//ExecuteString is like Execute but returns the output as a string.
+func (t *Template) ExecuteString(ctx interface{}, globs ...string) (string, error) {
+	return t.ExecuteBuilder(ctx, globs...)
+}
+
+//ExecuteBuilder is like ExecuteString, rendering into a strings.Builder so the
+//output is not copied to make the string.
+func (t *Template) ExecuteBuilder(ctx interface{}, globs ...string) (out string, err error) {
+	var b strings.Builder
+	b.Grow(t.sizeHint())
+	if err = t.Execute(&b, ctx, globs...); err != nil {
+		return
+	}
+	t.recordSize(b.Len())
+	out = b.String()
+	return
+}
+
+//ExecuteBytes is like Execute but returns the output as a byte slice.
+func (t *Template) ExecuteBytes(ctx interface{}, globs ...string) (out []byte, err error) {
+	buf := bytes.NewBuffer(make([]byte, 0, t.sizeHint()))
+	if err = t.Execute(buf, ctx, globs...); err != nil {
+		return
+	}
+	t.recordSize(buf.Len())
+	out = buf.Bytes()
+	return
+}
+
+//RetryIf sets the function ExecuteRetry uses to decide if an error is
+//transient and the render should be attempted again.
+func (t *Template) RetryIf(retryable func(error) bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.retryable = retryable
+	return t
+}
+
+//ExecuteRetry is like ExecuteAtomic but renders up to attempts times while the
+//error returned is retryable according to the function set with RetryIf. If no
+//function is set, no error is retried. Since every attempt is buffered, w only
+//ever receives the output of the successful one.
+func (t *Template) ExecuteRetry(w io.Writer, attempts int, ctx interface{}, globs ...string) (err error) {
+	t.compile_lock.RLock()
+	retryable := t.retryable
+	t.compile_lock.RUnlock()
+
+	var buf bytes.Buffer
+	for i := 1; ; i++ {
+		buf.Reset()
+		err = t.Execute(&buf, ctx, globs...)
+		if err == nil {
+			break
+		}
+		if retryable == nil || i >= attempts || !retryable(err) {
+			return
+		}
+	}
+	_, err = buf.WriteTo(w)
+	return
+}
+
+//ExecuteNamed is like Execute but also returns the name of the template that
+//was executed, which is the name of the fallback's file if the base template
+//fell back to it.
+func (t *Template) ExecuteNamed(w io.Writer, ctx interface{}, globs ...string) (rendered string, err error) {
+	res, err := t.ExecuteResult(w, ctx, globs...)
+	rendered = res.Template
+	return
+}
+
+//ExecuteTo is like Execute but writes the output to every one of the writers,
+//such as a response and an audit log. Writes go to the writers in order, and
+//the first writer to fail stops the Execute with an error naming its index.
+//The writers before it have then received the chunk that failed, while it
+//and the ones after it have not. Use ExecuteAtomic with an io.MultiWriter to
+//write either everything or nothing.
+func (t *Template) ExecuteTo(writers []io.Writer, ctx interface{}, globs ...string) error {
+	ws := make([]io.Writer, len(writers))
+	for i, w := range writers {
+		ws[i] = indexedWriter{w, i}
+	}
+	return t.Execute(io.MultiWriter(ws...), ctx, globs...)
+}
+
+//indexedWriter names the index of the writer in its write errors.
+type indexedWriter struct {
+	w io.Writer
+	i int
+}
+
+func (w indexedWriter) Write(p []byte) (n int, err error) {
+	if n, err = w.w.Write(p); err != nil {
+		err = fmt.Errorf("writer %d: %v", w.i, err)
+	}
+	return
+}
+
+//ExecuteFile is like Execute but writes the output to the file at path,
+//creating it and its parent directories as needed. The output is written to a
+//temporary file in the same directory that replaces path only once the
+//template executed successfully, so path never holds half of an output; on
+//error the temporary file is removed and path is left as it was.
+func (t *Template) ExecuteFile(path string, ctx interface{}, globs ...string) (err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = t.Execute(f, ctx, globs...); err != nil {
+		return
+	}
+	if err = f.Chmod(0644); err != nil {
+		return
+	}
+	if err = f.Close(); err != nil {
+		return
+	}
+	return os.Rename(f.Name(), path)
+}
+
+//ExecuteOrError is like ExecuteAtomic, but if the template fails it renders
+//errorTmpl with errCtx to w instead, so the client gets an error page rather
+//than half of a broken one. The original error is returned even when the error
+//page rendered; if the error page fails too, the error describes both. With a
+//nil errorTmpl nothing is written on error, as with ExecuteAtomic.
+func (t *Template) ExecuteOrError(w io.Writer, errorTmpl *Template, ctx, errCtx interface{}, globs ...string) (err error) {
+	if err = t.ExecuteAtomic(w, ctx, globs...); err == nil || errorTmpl == nil {
+		return
+	}
+	if perr := errorTmpl.ExecuteAtomic(w, errCtx); perr != nil {
+		return fmt.Errorf("%v; rendering error page %s: %v", err, errorTmpl.base, perr)
+	}
+	return
+}
+
+//Compose executes each of the parts with the context into w in order, as
+//sections of a single page, stopping at the first error. Every part executes
+//with its own base, blocks and functions, compiling as its Execute would, so
+//nothing is shared between them but the writer and the context. The error
+//names the part that failed.
+func Compose(w io.Writer, ctx interface{}, parts ...*Template) (err error) {
+	for i, part := range parts {
+		if err = part.Execute(w, ctx); err != nil {
+			return fmt.Errorf("part %d %s: %v", i, part.base, err)
+		}
+	}
+	return
+}
@@ -0,0 +1,138 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecuteRetry(t *testing.T) {
+	errMiss := errors.New("cache miss")
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `<p>start</p>{% fetch %}`})
+
+	var calls int
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Call("fetch", func() (string, error) {
+		if calls++; calls < 3 {
+			return "", errMiss
+		}
+		return "value", nil
+	})
+
+	//without RetryIf nothing is retried and nothing is written
+	var buf strings.Builder
+	if err := tm.ExecuteRetry(&buf, 5, nil); !errors.Is(err, errMiss) || buf.Len() != 0 || calls != 1 {
+		t.Fatalf("got %q, %v after %d calls", buf.String(), err, calls)
+	}
+
+	calls = 0
+	tm.RetryIf(func(err error) bool { return errors.Is(err, errMiss) })
+	if err := tm.ExecuteRetry(&buf, 2, nil); !errors.Is(err, errMiss) || buf.Len() != 0 || calls != 2 {
+		t.Fatalf("limited attempts: got %q, %v after %d calls", buf.String(), err, calls)
+	}
+
+	calls = 0
+	if err := tm.ExecuteRetry(&buf, 5, nil); err != nil || buf.String() != "<p>start</p>value" || calls != 3 {
+		t.Fatalf("got %q, %v after %d calls", buf.String(), err, calls)
+	}
+}
+
+func BenchmarkExecuteBuilder(b *testing.B) {
+	tm := benchmarkSmall(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := tm.ExecuteBuilder("world"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecuteBuffer(b *testing.B) {
+	tm := benchmarkSmall(b)
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		buf.Grow(tm.sizeHint())
+		if err := tm.Execute(&buf, "world"); err != nil {
+			b.Fatal(err)
+		}
+		_ = buf.String()
+	}
+}
+
+//benchmarkSmall returns a template rendering a small fragment, already
+//compiled, and resets the timer.
+func benchmarkSmall(b *testing.B) *Template {
+	dir := b.TempDir()
+	writeFiles(b, dir, map[string]string{"base.tmpl": `<p class="greeting">Hello, {% . %}!</p>`})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	if _, err := tm.ExecuteBuilder("world"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	return tm
+}
+
+func TestExecuteFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `<p>{% fail . %}</p>`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Call("fail", func(s string) (string, error) {
+		if s == "" {
+			return "", errors.New("empty")
+		}
+		return s, nil
+	})
+
+	path := filepath.Join(dir, "out", "page.html")
+	if err := tm.ExecuteFile(path, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "<p>first</p>" {
+		t.Fatalf("got %q, %v", data, err)
+	}
+
+	//a failed render keeps the old file and leaves nothing behind
+	if err := tm.ExecuteFile(path, ""); err == nil {
+		t.Fatal("render did not fail")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "<p>first</p>" {
+		t.Errorf("after a failed render got %q, %v", data, err)
+	}
+	if err := tm.ExecuteFile(filepath.Join(dir, "out", "new.html"), ""); err == nil {
+		t.Fatal("render did not fail")
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "page.html" {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("files left: %q", names)
+	}
+}
+
+func TestExecuteOrError(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl":  `<p>{% .Missing.Field %}</p>`,
+		"error.tmpl": `<h1>{% . %}</h1>`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	ctx := map[string]int{"Missing": 1}
+
+	var buf strings.Builder
+	err := tm.ExecuteOrError(&buf, Parse(filepath.Join(dir, "error.tmpl")), ctx, "oops")
+	if err == nil || buf.String() != "<h1>oops</h1>" {
+		t.Errorf("got %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := tm.ExecuteOrError(&buf, nil, ctx, "oops"); err == nil || buf.Len() != 0 {
+		t.Errorf("nil error page: got %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,142 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+//OutputFilter adds a function that transforms the rendered output of every
+//Execute before it is written, such as a minifier. Filters run in the order
+//they were added, each on the output of the one before. Since they need the
+//whole output, it is buffered first; if a filter returns an error, nothing is
+//written and the Execute returns it. MaxOutputBytes limits the output before
+//filtering.
+func (t *Template) OutputFilter(fn func([]byte) ([]byte, error)) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.filters = append(t.filters, fn)
+	return t
+}
+
+//filtering reports if the output of an Execute has to go through
+//executeFiltered. The caller must hold at least the read lock.
+func (t *Template) filtering() bool {
+	return len(t.filters) > 0 || t.no_value || t.pretty != FormatNone || t.line_endings != Preserve
+}
+
+//executeFiltered executes tmpl into a buffer, runs the output filters over it,
+//checking for missing values first and pretty printing and converting line
+//endings last if asked to, and writes the result to w, returning the number of
+//bytes written. The caller must hold the read lock.
+func (t *Template) executeFiltered(tmpl *template.Template, w io.Writer, ctx interface{}) (n int64, err error) {
+	cw := newCountWriter(w, 0)
+
+	var buf bytes.Buffer
+	bw := &countWriter{w: &buf, max: t.max_output, done: cw.done}
+	if err = tmpl.Execute(bw, ctx); err != nil {
+		return
+	}
+
+	out, err := t.filter(buf.Bytes())
+	if err != nil {
+		return
+	}
+	_, err = cw.Write(out)
+	n = cw.n
+	return
+}
+
+//filter runs the rendered output through the checks and filters
+//executeFiltered does. The caller must hold the read lock.
+func (t *Template) filter(out []byte) (_ []byte, err error) {
+	//capped so appending never writes into the array shared by the Executes
+	filters := t.filters[:len(t.filters):len(t.filters)]
+	if t.no_value {
+		filters = append([]func([]byte) ([]byte, error){checkNoValue}, filters...)
+	}
+	if t.pretty != FormatNone {
+		filters = append(filters, t.pretty.indent)
+	}
+	if t.line_endings != Preserve {
+		filters = append(filters, t.line_endings.convert)
+	}
+
+	for _, fn := range filters {
+		if out, err = fn(out); err != nil {
+			return
+		}
+	}
+	return out, nil
+}
+
+//ErrorOnNoValue sets if Execute fails when the output shows a missing value,
+//to catch broken bindings in tests: <no value>, which the template package
+//writes for values it could not find, or <nil>, which is what a nil value
+//printed through fmt, as by print and printf, becomes once escaped. A plain
+//action on a missing value writes nothing in html/template, so it is not
+//caught. Like an OutputFilter, it buffers the output and writes nothing on
+//error. The error quotes the output around the first missing value.
+func (t *Template) ErrorOnNoValue(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.no_value = on
+	return t
+}
+
+//noValues are the ways missing values show up in the output.
+var noValues = [][]byte{[]byte("<no value>"), []byte("&lt;no value&gt;"), []byte("&lt;nil&gt;")}
+
+//checkNoValue returns an error quoting the output around the first missing
+//value.
+func checkNoValue(out []byte) ([]byte, error) {
+	for _, nv := range noValues {
+		i := bytes.Index(out, nv)
+		if i < 0 {
+			continue
+		}
+		start, end := i-40, i+len(nv)+40
+		if start < 0 {
+			start = 0
+		}
+		if end > len(out) {
+			end = len(out)
+		}
+		return nil, fmt.Errorf("output contains %s at byte %d: %q", nv, i, out[start:end])
+	}
+	return out, nil
+}
+
+//LineEndingStyle is the line ending LineEndings converts the output to.
+type LineEndingStyle int
+
+const (
+	Preserve LineEndingStyle = iota //leave the line endings as they are
+	LF                              //end every line with \n
+	CRLF                            //end every line with \r\n
+)
+
+//LineEndings sets the line ending every line of the output is converted to,
+//for consistent output whatever the editor the templates were written with.
+//Lone \r bytes are left alone. It is meant for text output, since it changes
+//the bytes of anything embedded in it too. Like an OutputFilter, it buffers the
+//output, and it runs after the filters.
+func (t *Template) LineEndings(style LineEndingStyle) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.line_endings = style
+	return t
+}
+
+//convert returns out with its line endings in the style.
+func (s LineEndingStyle) convert(out []byte) ([]byte, error) {
+	lf := bytes.ReplaceAll(out, []byte("\r\n"), []byte("\n"))
+	if s == CRLF {
+		return bytes.ReplaceAll(lf, []byte("\n"), []byte("\r\n")), nil
+	}
+	return lf, nil
+}
@@ -0,0 +1,81 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestLineEndingsConvert(t *testing.T) {
+	cases := []struct {
+		in       string
+		lf, crlf string
+	}{
+		{"a\nb\n", "a\nb\n", "a\r\nb\r\n"},
+		{"a\r\nb\r\n", "a\nb\n", "a\r\nb\r\n"},
+		{"a\r\nb\nc", "a\nb\nc", "a\r\nb\r\nc"},
+		{"a\rb\r", "a\rb\r", "a\rb\r"},
+		{"a\r\r\nb", "a\r\nb", "a\r\r\nb"},
+		{"\n\r\n", "\n\n", "\r\n\r\n"},
+		{"", "", ""},
+	}
+	for _, c := range cases {
+		if got, _ := LF.convert([]byte(c.in)); string(got) != c.lf {
+			t.Errorf("LF %q: got %q, want %q", c.in, got, c.lf)
+		}
+		if got, _ := CRLF.convert([]byte(c.in)); string(got) != c.crlf {
+			t.Errorf("CRLF %q: got %q, want %q", c.in, got, c.crlf)
+		}
+	}
+}
+
+func TestLineEndingsExecute(t *testing.T) {
+	dir := t.TempDir()
+	//the \r ends the text of the template and the \n starts the value, so
+	//they reach the output in two writes
+	writeFiles(t, dir, map[string]string{"base.tmpl": "a\r\nb\r{% .Rest %}\r\n"})
+	ctx := map[string]string{"Rest": "\nc\rd"}
+
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	for _, c := range []struct {
+		style LineEndingStyle
+		want  string
+	}{
+		{Preserve, "a\r\nb\r\nc\rd\r\n"},
+		{LF, "a\nb\nc\rd\n"},
+		{CRLF, "a\r\nb\r\nc\rd\r\n"},
+	} {
+		tm.LineEndings(c.style)
+		if out, err := tm.ExecuteString(ctx); err != nil || out != c.want {
+			t.Errorf("style %d: got %q, %v, want %q", c.style, out, err, c.want)
+		}
+	}
+}
+
+func TestFiltersConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": "a\n"})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	//three filters leave room in the slice for the built in ones to be
+	//appended into
+	for i := 0; i < 3; i++ {
+		tm.OutputFilter(func(out []byte) ([]byte, error) { return bytes.ToUpper(out), nil })
+	}
+	tm.LineEndings(CRLF)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if out, err := tm.ExecuteString(nil); err != nil || out != "A\r\n" {
+					t.Errorf("got %q, %v", out, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,127 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+//ExecuteFragments executes each of the named templates defined in the template
+//with the context and returns their outputs keyed by name. The template is
+//compiled and the globs attached once for all of the fragments, so the base
+//itself can be one of them under the name of its file. If any of the names is
+//not defined, fails to execute or is not valid JSON when it has to be, see
+//ValidateJSON, the error names the fragment.
+func (t *Template) ExecuteFragments(ctx interface{}, names []string, globs ...string) (frags map[string]string, err error) {
+	var res Result
+	err = t.render(globs, &res, func(tmpl *template.Template) (err error) {
+		if ctx, err = t.prepare(ctx); err != nil {
+			return
+		}
+
+		frags = make(map[string]string, len(names))
+		for _, name := range names {
+			var buf bytes.Buffer
+			cw := newCountWriter(&buf, t.max_output)
+			if err = tmpl.ExecuteTemplate(cw, name, ctx); err != nil {
+				return fmt.Errorf("fragment %q: %v", name, err)
+			}
+			if t.json_fragments[name] {
+				if err = checkJSON(buf.String()); err != nil {
+					return fmt.Errorf("fragment %q: %v", name, err)
+				}
+			}
+			frags[name] = buf.String()
+		}
+		return
+	})
+	if err != nil {
+		frags = nil
+	}
+	return
+}
+
+//ExecuteStreamFragments executes each of the named templates in order into w,
+//writing sep between them, for streaming components to the client as they are
+//ready. After every fragment w is flushed if it can be, like an
+//http.ResponseWriter or a bufio.Writer. The stream stops at the first fragment
+//that fails, with an error naming it; the fragments before it have already
+//been written.
+func (t *Template) ExecuteStreamFragments(w io.Writer, names []string, sep []byte, ctx interface{}, globs ...string) (err error) {
+	var res Result
+	err = t.render(globs, &res, func(tmpl *template.Template) (err error) {
+		if ctx, err = t.prepare(ctx); err != nil {
+			return
+		}
+
+		cw := newCountWriter(w, t.max_output)
+		for i, name := range names {
+			if i > 0 {
+				if _, err = cw.Write(sep); err != nil {
+					return fmt.Errorf("fragment %q: %v", name, err)
+				}
+			}
+			if err = tmpl.ExecuteTemplate(cw, name, ctx); err != nil {
+				return fmt.Errorf("fragment %q: %v", name, err)
+			}
+			if err = flush(w); err != nil {
+				return fmt.Errorf("fragment %q: %v", name, err)
+			}
+		}
+		return
+	})
+	return
+}
+
+//flush flushes w if it is buffered.
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+//ValidateJSON sets the names of fragments that ExecuteFragments checks render
+//valid JSON, like structured data blocks. A fragment that renders a whole
+//<script type="application/ld+json"> element is checked by its content.
+func (t *Template) ValidateJSON(names ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	if t.json_fragments == nil {
+		t.json_fragments = map[string]bool{}
+	}
+	for _, name := range names {
+		t.json_fragments[name] = true
+	}
+	return t
+}
+
+//checkJSON returns an error describing why out, or the content of the script
+//element it consists of, is not valid JSON.
+func checkJSON(out string) error {
+	src := strings.TrimSpace(out)
+	if strings.HasPrefix(strings.ToLower(src), "<script") {
+		start := strings.IndexByte(src, '>') + 1
+		end := strings.LastIndex(strings.ToLower(src), "</script")
+		if start == 0 || end < start {
+			return fmt.Errorf("unterminated script element")
+		}
+		src = src[start:end]
+	}
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(src), &v); err != nil {
+		if serr, ok := err.(*json.SyntaxError); ok {
+			return fmt.Errorf("invalid JSON at offset %d: %v", serr.Offset, err)
+		}
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return nil
+}
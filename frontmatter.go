@@ -0,0 +1,85 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+)
+
+//WithFrontMatter makes Compile strip front matter from the start of the base
+//file: lines between a first line of --- and the next line of ---, as
+//static site generators use. It is decoded into a map with unmarshal, such as
+//yaml.Unmarshal, so there is no dependency on a YAML package. The map is
+//returned by FrontMatter, and templates can look its keys up with meta, as in
+//{% meta "title" %}; keys passed to ExecuteWithData take precedence. A base
+//without front matter is parsed unchanged.
+func (t *Template) WithFrontMatter(unmarshal func(data []byte, v interface{}) error) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.unmarshal_front = unmarshal
+	t.dirty = true
+	return t
+}
+
+//FrontMatter returns a copy of the front matter of the base as of the last
+//Compile, or nil if it had none or WithFrontMatter is not set.
+func (t *Template) FrontMatter() map[string]interface{} {
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	if t.front == nil {
+		return nil
+	}
+	front := make(map[string]interface{}, len(t.front))
+	for k, v := range t.front {
+		front[k] = v
+	}
+	return front
+}
+
+//parseFrontMatter is like parseFiles for the base, stripping and decoding its
+//front matter first. The front matter is replaced by a comment spanning as
+//many lines, so line numbers in errors still match the file.
+func (t *Template) parseFrontMatter(tmpl *template.Template, base string) (_ *template.Template, front map[string]interface{}, err error) {
+	data, err := t.readFile(base)
+	if err != nil {
+		return
+	}
+
+	matter, rest, ok := splitFrontMatter(data)
+	if ok {
+		if err = t.unmarshal_front(matter, &front); err != nil {
+			err = fmt.Errorf("%s: front matter: %v", base, err)
+			return
+		}
+		left, right := t.delims()
+		lines := bytes.Repeat([]byte("\n"), bytes.Count(data[:len(data)-len(rest)], []byte("\n")))
+		rest = append([]byte(left+"/*"+string(lines)+"*/"+right), rest...)
+	}
+
+	if filepath.Base(base) != tmpl.Name() {
+		tmpl = tmpl.New(filepath.Base(base))
+	}
+	_, err = tmpl.Parse(string(rest))
+	return tmpl, front, err
+}
+
+//splitFrontMatter splits data into its front matter and the rest, reporting
+//if it has any. Front matter has to start on the first line and be closed.
+func splitFrontMatter(data []byte) (matter, rest []byte, ok bool) {
+	line, after, found := bytes.Cut(data, []byte("\n"))
+	if !found || string(bytes.TrimRight(line, "\r")) != "---" {
+		return nil, data, false
+	}
+
+	for start := after; len(after) > 0; {
+		line, next, _ := bytes.Cut(after, []byte("\n"))
+		if string(bytes.TrimRight(line, "\r")) == "---" {
+			return start[:len(start)-len(after)], next, true
+		}
+		after = next
+	}
+	return nil, data, false
+}
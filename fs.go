@@ -0,0 +1,151 @@
+package tmplmgr
+
+import (
+	"archive/zip"
+	"context"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//ParseFS is like Parse but reads the base file and every glob attached to the
+//template from fsys instead of the operating system's file system. Globs use
+//the slash separated syntax of fs.Glob.
+func ParseFS(fsys fs.FS, file string) *Template {
+	t := Parse(file)
+	t.fsys = fsys
+	return t
+}
+
+//ParseZip is like ParseFS reading the templates from the zip archive. The
+//contents of an archive can't change, so Development mode will not recompile
+//the template on every Execute; it still recompiles after it is modified.
+func ParseZip(r *zip.Reader, file string) *Template {
+	t := ParseFS(r, file)
+	t.immutable = true
+	return t
+}
+
+//development reports if the template should be compiled on every Execute.
+func (t *Template) development() bool {
+	return t.developmentIn(context.Background())
+}
+
+//compileMode returns the mode set with SetMode, or the package's. The caller
+//must hold at least the read lock.
+func (t *Template) compileMode() Mode {
+	if t.mode_set {
+		return t.mode
+	}
+	return compile_mode
+}
+
+//developmentIn is like development for an Execute with the context c, using
+//the mode it carries, if any, instead of the template's.
+func (t *Template) developmentIn(c context.Context) bool {
+	mode := t.compileMode()
+	if m, ok := c.Value(modeKey{}).(Mode); ok {
+		mode = m
+	}
+	return mode == Development && !t.immutable && !t.background
+}
+
+//parseFiles parses the named files into tmpl.
+func (t *Template) parseFiles(tmpl *template.Template, files ...string) (*template.Template, error) {
+	if t.fsys != nil {
+		return tmpl.ParseFS(t.fsys, files...)
+	}
+	return tmpl.ParseFiles(files...)
+}
+
+//parseGlob parses the files matching the glob into tmpl.
+func (t *Template) parseGlob(tmpl *template.Template, glob string) (*template.Template, error) {
+	if t.fsys != nil {
+		return tmpl.ParseFS(t.fsys, glob)
+	}
+	return tmpl.ParseGlob(glob)
+}
+
+//matchFiles returns the base followed by every file matched by the globs
+//attached to the template and the ones passed in, and then the files attached
+//with Files that are not among them. The caller must hold at least the read
+//lock.
+func (t *Template) matchFiles(globs []string) (files []string, err error) {
+	if files, err = t.matchGlobs(globs); err != nil {
+		return
+	}
+	return append(files, t.extraFiles(files)...), nil
+}
+
+//matchGlobs is like matchFiles without the files attached with Files.
+func (t *Template) matchGlobs(globs []string) (files []string, err error) {
+	all := append([]string{}, t.blocks...)
+	for _, g := range t.groups {
+		all = append(all, g.globs...)
+	}
+	all = append(all, globs...)
+
+	files = []string{t.base}
+	for _, glob := range all {
+		var matches []string
+		if matches, err = t.globFiles(glob); err != nil {
+			return
+		}
+		files = append(files, matches...)
+	}
+	return
+}
+
+//readFile returns the contents of the named file.
+func (t *Template) readFile(name string) ([]byte, error) {
+	if t.fsys != nil {
+		return fs.ReadFile(t.fsys, name)
+	}
+	return os.ReadFile(name)
+}
+
+//globFiles returns the files matching the glob.
+func (t *Template) globFiles(glob string) ([]string, error) {
+	if t.fsys != nil {
+		return fs.Glob(t.fsys, glob)
+	}
+	return filepath.Glob(glob)
+}
+
+//extraFiles returns the files attached with Files that are not among matched,
+//each once.
+func (t *Template) extraFiles(matched []string) (files []string) {
+	seen := map[string]bool{}
+	for _, file := range matched {
+		seen[filepath.Clean(file)] = true
+	}
+	for _, file := range t.files {
+		if !seen[filepath.Clean(file)] {
+			seen[filepath.Clean(file)] = true
+			files = append(files, file)
+		}
+	}
+	return
+}
+
+//resolve returns the path of the file after following any symlinks, or the
+//name itself for file systems other than the os or if it can't be resolved.
+func (t *Template) resolve(name string) string {
+	if t.fsys != nil {
+		return name
+	}
+	path, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return name
+	}
+	return path
+}
+
+//stat returns the FileInfo for the named file.
+func (t *Template) stat(name string) (fs.FileInfo, error) {
+	if t.fsys != nil {
+		return fs.Stat(t.fsys, name)
+	}
+	return os.Stat(name)
+}
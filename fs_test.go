@@ -0,0 +1,54 @@
+package tmplmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"v1/base.tmpl": `version 1`,
+		"v2/base.tmpl": `version 2`,
+	})
+	//both targets look the same but for their path
+	stamp := time.Now().Add(-time.Hour)
+	for _, v := range []string{"v1", "v2"} {
+		if err := os.Chtimes(filepath.Join(dir, v, "base.tmpl"), stamp, stamp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	current := filepath.Join(dir, "current")
+	if err := os.Symlink(filepath.Join(dir, "v1"), current); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+
+	tm := Parse(filepath.Join(current, "base.tmpl")).SetMode(Development)
+	if out, err := tm.ExecuteString(nil); err != nil || out != "version 1" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	tm.compile_lock.RLock()
+	before := tm.fileStamp()
+	tm.compile_lock.RUnlock()
+
+	//swap the link atomically the way a deploy does
+	next := filepath.Join(dir, "next")
+	if err := os.Symlink(filepath.Join(dir, "v2"), next); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(next, current); err != nil {
+		t.Fatal(err)
+	}
+
+	tm.compile_lock.RLock()
+	after := tm.fileStamp()
+	tm.compile_lock.RUnlock()
+	if before == after {
+		t.Errorf("swap not seen in the file stamp %q", after)
+	}
+	if out, err := tm.ExecuteString(nil); err != nil || out != "version 2" {
+		t.Fatalf("after the swap got %q, %v", out, err)
+	}
+}
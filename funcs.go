@@ -0,0 +1,267 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+//default_funcs holds the functions attached to every template, see
+//DefaultFuncs.
+var default_funcs = struct {
+	sync.RWMutex
+	funcs template.FuncMap
+}{funcs: template.FuncMap{}}
+
+//DefaultFuncs attaches the functions to every template beneath the ones
+//attached with Call, which take precedence for the same name. They are picked
+//up by the next compile of each template, so they should be set before any
+//template is compiled.
+func DefaultFuncs(funcs template.FuncMap) {
+	default_funcs.Lock()
+	defer default_funcs.Unlock()
+
+	for name, fnc := range funcs {
+		default_funcs.funcs[name] = fnc
+	}
+}
+
+//defaultFuncs returns a copy of the functions set with DefaultFuncs.
+func defaultFuncs() template.FuncMap {
+	default_funcs.RLock()
+	defer default_funcs.RUnlock()
+
+	funcs := make(template.FuncMap, len(default_funcs.funcs))
+	for name, fnc := range default_funcs.funcs {
+		funcs[name] = fnc
+	}
+	return funcs
+}
+
+//WarnShadowing sets if Compile logs every function attached with Call that
+//overrides one set with DefaultFuncs. In Development mode they are always
+//logged.
+func (t *Template) WarnShadowing(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.warn_shadowing = on
+	return t
+}
+
+//warnShadowed logs the names of the functions attached with Call that
+//override the defaults, in name order, returning them as warnings. The caller
+//must hold the write lock.
+func (t *Template) warnShadowed(defaults template.FuncMap) (warnings []string) {
+	var names []string
+	for name := range t.funcs {
+		if _, ex := defaults[name]; ex {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		warnings = append(warnings, fmt.Sprintf("func %s overrides the default", name))
+	}
+
+	if t.warn_shadowing || t.compileMode() == Development {
+		for _, w := range warnings {
+			logf("%s: %s", t.base, w)
+		}
+	}
+	return
+}
+
+//CallSafe is like Call but makes the function best effort: if it panics, the
+//panic is logged and the action calling it renders as empty instead of failing
+//the whole Execute.
+func (t *Template) CallSafe(name string, fnc interface{}) *Template {
+	return t.Call(name, safeFunc(name, fnc))
+}
+
+//emptyString is the value an interface result of a panicking safe function
+//takes so the action renders nothing rather than a nil value.
+var emptyString = reflect.ValueOf("")
+
+//safeFunc wraps fnc with a recover that logs the panic and returns the zero
+//values of its results. Values that aren't functions are returned as is so
+//Compile reports them.
+func safeFunc(name string, fnc interface{}) interface{} {
+	v := reflect.ValueOf(fnc)
+	if v.Kind() != reflect.Func {
+		return fnc
+	}
+
+	typ := v.Type()
+	return reflect.MakeFunc(typ, func(args []reflect.Value) (out []reflect.Value) {
+		defer func() {
+			if e := recover(); e != nil {
+				logf("recovered panic in %s: %v", name, e)
+				out = make([]reflect.Value, typ.NumOut())
+				for i := range out {
+					out[i] = reflect.Zero(typ.Out(i))
+				}
+				if len(out) > 0 && typ.Out(0).Kind() == reflect.Interface && emptyString.Type().Implements(typ.Out(0)) {
+					out[0] = emptyString.Convert(typ.Out(0))
+				}
+			}
+		}()
+
+		if typ.IsVariadic() {
+			return v.CallSlice(args)
+		}
+		return v.Call(args)
+	}).Interface()
+}
+
+//Features sets feature flags for the template, merged with any set before, and
+//attaches a feature function reporting them so templates can do
+//{% if feature "beta" %}. Flags that were never set are false. ExecuteFeatures
+//overrides them for a single Execute.
+func (t *Template) Features(features map[string]bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	if t.features == nil {
+		t.features = map[string]bool{}
+		t.funcs["feature"] = t.feature
+	}
+	for name, on := range features {
+		t.features[name] = on
+	}
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//feature is the function attached by Features. It is only called while
+//executing, when the read lock is held.
+func (t *Template) feature(name string) bool {
+	return t.features[name]
+}
+
+//Pipeline attaches a function under name that passes its argument through the
+//named functions in order, so {% .Name | clean %} can stand for
+//{% .Name | trim | lower %}. The functions must already be attached with Call
+//or DefaultFuncs, and attaching others under their names later does not change
+//the pipeline. They must take a single argument the one before can pass to
+//them, and return a value or a value and an error. An error from any of them
+//stops the pipeline and fails the Execute. If the functions don't fit together,
+//every Compile and Execute fails saying why until name is attached again.
+func (t *Template) Pipeline(name string, funcs ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	fnc, err := t.pipeline(funcs)
+	if err != nil {
+		if t.pipeline_errs == nil {
+			t.pipeline_errs = map[string]error{}
+		}
+		t.pipeline_errs[name] = fmt.Errorf("pipeline %s: %v", name, err)
+		delete(t.funcs, name)
+	} else {
+		delete(t.pipeline_errs, name)
+		t.funcs[name] = fnc
+	}
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//pipelineErr returns the error of the first pipeline by name that could not be
+//composed, if any. The caller must hold at least the read lock.
+func (t *Template) pipelineErr() error {
+	names := make([]string, 0, len(t.pipeline_errs))
+	for name := range t.pipeline_errs {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return t.pipeline_errs[names[0]]
+}
+
+//pipeline composes the named functions. The caller must hold the write lock.
+func (t *Template) pipeline(names []string) (interface{}, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no functions")
+	}
+
+	defaults := defaultFuncs()
+	steps := make([]reflect.Value, len(names))
+	for i, name := range names {
+		fnc, ok := t.funcs[name]
+		if !ok {
+			if fnc, ok = defaults[name]; !ok {
+				return nil, fmt.Errorf("no function %s", name)
+			}
+		}
+
+		v := reflect.ValueOf(fnc)
+		if !v.IsValid() {
+			return nil, fmt.Errorf("%s is nil, not a function", name)
+		}
+		typ := v.Type()
+		switch {
+		case v.Kind() != reflect.Func:
+			return nil, fmt.Errorf("%s is a %v, not a function", name, typ)
+		case typ.NumIn() != 1 || typ.IsVariadic():
+			return nil, fmt.Errorf("%s must take a single argument", name)
+		case typ.NumOut() != 1 && (typ.NumOut() != 2 || typ.Out(1) != errorType):
+			return nil, fmt.Errorf("%s must return one value, or a value and an error", name)
+		case i > 0 && !steps[i-1].Type().Out(0).AssignableTo(typ.In(0)):
+			return nil, fmt.Errorf("%s takes a %v, but %s returns a %v", name, typ.In(0), names[i-1], steps[i-1].Type().Out(0))
+		}
+		steps[i] = v
+	}
+
+	in := steps[0].Type().In(0)
+	out := steps[len(steps)-1].Type().Out(0)
+	typ := reflect.FuncOf([]reflect.Type{in}, []reflect.Type{out, errorType}, false)
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		v := args[0]
+		for _, step := range steps {
+			res := step.Call([]reflect.Value{v})
+			if len(res) == 2 && !res[1].IsNil() {
+				return []reflect.Value{reflect.Zero(out), res[1]}
+			}
+			v = res[0]
+		}
+		return []reflect.Value{v, reflect.Zero(errorType)}
+	}).Interface(), nil
+}
+
+//CallSet attaches a function to the template under name that is made for each
+//compiled set by calling fnc with it, so the function can look at the set, as
+//in a partialExists helper calling Lookup. Every set the globs passed to
+//Execute are parsed into gets a function of its own, seeing their definitions.
+func (t *Template) CallSet(name string, fnc func(set *template.Template) interface{}) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	if t.set_funcs == nil {
+		t.set_funcs = map[string]func(*template.Template) interface{}{}
+	}
+	//the placeholder lets templates parse before there is a set
+	t.funcs[name] = func() string { return "" }
+	t.set_funcs[name] = fnc
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//bindSetFuncs makes the functions attached with CallSet for the set. It must
+//be called on every set before it first executes.
+func (t *Template) bindSetFuncs(tmpl *template.Template) {
+	if len(t.set_funcs) == 0 {
+		return
+	}
+	funcs := template.FuncMap{}
+	for name, fnc := range t.set_funcs {
+		funcs[name] = fnc(tmpl)
+	}
+	tmpl.Funcs(funcs)
+}
@@ -0,0 +1,112 @@
+package tmplmgr
+
+import (
+	"html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeCallSet(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% if has "p" %}{% template "p" %}{% end %}{% if has "q" %}q{% end %}`,
+		"p.tmpl":    `{% define "p" %}P{% end %}`,
+	})
+	has := func(set *template.Template) interface{} {
+		return func(name string) bool { return set.Lookup(name) != nil }
+	}
+	other := Parse(filepath.Join(dir, "base.tmpl")).Blocks(filepath.Join(dir, "p.tmpl")).CallSet("has", has)
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Merge(other)
+	if out, err := tm.ExecuteString(nil); err != nil || out != "P" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+
+	//the template's own function wins
+	own := Parse(filepath.Join(dir, "base.tmpl")).Call("has", func(string) bool { return true }).Merge(other)
+	if out, err := own.ExecuteString(nil); err != nil || out != "Pq" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+}
+
+func TestExecuteFeatures(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% if feature "a" %}a{% end %}{% if feature "b" %}b{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	execute := func(overrides map[string]bool) string {
+		t.Helper()
+		var b strings.Builder
+		if err := tm.ExecuteFeatures(&b, overrides, nil); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	if out := execute(map[string]bool{"b": true}); out != "b" {
+		t.Fatalf("without Features got %q", out)
+	}
+
+	tm.Features(map[string]bool{"a": true})
+	if out, err := tm.ExecuteString(nil); err != nil || out != "a" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if out := execute(map[string]bool{"a": false, "b": true}); out != "b" {
+		t.Fatalf("with overrides got %q", out)
+	}
+	if out := execute(nil); out != "a" {
+		t.Fatalf("without overrides got %q", out)
+	}
+	if out, _ := tm.ExecuteString(nil); out != "a" {
+		t.Fatalf("overrides leaked into a later Execute: %q", out)
+	}
+}
+
+func TestCallSafeNilInput(t *testing.T) {
+	type user struct{ Name string }
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `[{% name .A %}|{% name .B %}|{% greet .B %}]`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).
+		CallSafe("name", func(u *user) string { return u.Name }).
+		CallSafe("greet", func(u *user) interface{} { return "hi " + u.Name })
+
+	var out string
+	var err error
+	logged := CaptureOutput(func() {
+		out, err = tm.ExecuteString(map[string]*user{"A": {Name: "a"}, "B": nil})
+	})
+	if err != nil || out != "[a||]" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if !strings.Contains(logged, "recovered panic in name") || !strings.Contains(logged, "recovered panic in greet") {
+		t.Errorf("panics not logged: %q", logged)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% "  A " | clean %}`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).
+		Call("trim", strings.TrimSpace).
+		Call("none", nil).
+		Pipeline("clean", "trim", "none")
+
+	if err := tm.Compile(); err == nil || !strings.Contains(err.Error(), "pipeline clean: none is nil") {
+		t.Errorf("Compile: %v", err)
+	}
+	if _, err := tm.ExecuteString(nil); err == nil || !strings.Contains(err.Error(), "pipeline clean") {
+		t.Errorf("Execute: %v", err)
+	}
+
+	tm.Pipeline("clean", "trim", "nope")
+	if err := tm.Compile(); err == nil || !strings.Contains(err.Error(), "no function nope") {
+		t.Errorf("Compile: %v", err)
+	}
+
+	tm.Call("none", strings.ToLower).Pipeline("clean", "trim", "none")
+	if out, err := tm.ExecuteString(nil); err != nil || out != "a" {
+		t.Errorf("fixed pipeline: got %q, %v", out, err)
+	}
+}
@@ -0,0 +1,113 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"html/template"
+	"text/template/parse"
+)
+
+//blockGroup is a set of globs parsed apart from the rest of the template, with
+//their own delimiters or extra functions, and merged in afterward.
+type blockGroup struct {
+	globs       []string
+	left, right string
+	funcs       template.FuncMap
+}
+
+//BlocksWith attaches the block definitions in the files matching the globs
+//like Blocks, but parses them using the given delimiters instead of the ones
+//the rest of the template uses (see Delims). This allows pulling in blocks
+//written for another delimiter style, like the {{ and }} of the standard
+//library. Only the files matching these globs are affected; the base, other
+//Blocks and the globs passed to Execute keep using the template's delimiters.
+//The blocks are merged in after the ones attached with Blocks, so their
+//definitions take precedence.
+func (t *Template) BlocksWith(left, right string, globs ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.groups = append(t.groups, blockGroup{
+		globs: globs,
+		left:  left,
+		right: right,
+	})
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//ScopedCall attaches a function under the specified name that only the block
+//definitions in the files matching blockGlob can call. The blocks are merged
+//in after the ones attached with Blocks, so their definitions take precedence.
+//Other templates calling name fail to compile as if it was never attached.
+func (t *Template) ScopedCall(blockGlob, name string, fnc interface{}) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.dirty = true
+	t.clearCache()
+	for _, g := range t.groups {
+		if g.funcs != nil && len(g.globs) == 1 && g.globs[0] == blockGlob {
+			g.funcs[name] = fnc
+			return t
+		}
+	}
+	t.groups = append(t.groups, blockGroup{
+		globs: []string{blockGlob},
+		funcs: template.FuncMap{name: fnc},
+	})
+	return t
+}
+
+//scopedName is the name the function of the i'th block group is attached to
+//the whole set under, chosen so it won't clash with names used in templates.
+func scopedName(i int, name string) string {
+	return fmt.Sprintf("_scoped%d_%s", i, name)
+}
+
+//parseGroups parses every block group separately with its delimiters and
+//extra functions, rewrites the calls to its functions to their scoped names,
+//and merges the definitions into tmpl. The caller must hold the write lock.
+func (t *Template) parseGroups(tmpl *template.Template) (err error) {
+	for i, g := range t.groups {
+		left, right := g.left, g.right
+		if left == "" && right == "" {
+			left, right = t.delims()
+		}
+
+		sub := template.New("")
+		sub.Funcs(requestFuncs).Funcs(defaultFuncs()).Funcs(t.funcs).Funcs(g.funcs)
+		sub.Delims(left, right)
+		for _, glob := range g.globs {
+			sub, err = t.parseGlob(sub, glob)
+			if err != nil {
+				return
+			}
+		}
+
+		renamed := template.FuncMap{}
+		for name, fnc := range g.funcs {
+			renamed[scopedName(i, name)] = fnc
+		}
+		tmpl.Funcs(renamed)
+
+		for _, x := range sub.Templates() {
+			if x.Tree == nil || x.Name() == "" {
+				continue
+			}
+			if len(g.funcs) > 0 {
+				walk(x.Tree.Root, func(node parse.Node) {
+					if n, ok := node.(*parse.IdentifierNode); ok {
+						if _, ex := g.funcs[n.Ident]; ex {
+							n.Ident = scopedName(i, n.Ident)
+						}
+					}
+				})
+			}
+			if _, err = tmpl.AddParseTree(x.Name(), x.Tree); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
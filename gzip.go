@@ -0,0 +1,35 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+//ExecuteGzipIf is like ExecuteAtomic but gzips the output if it is longer than
+//minBytes, since compressing small outputs costs more than it saves. It
+//reports whether the output was gzipped, so the caller knows whether to set
+//Content-Encoding: gzip. On error nothing is written to w.
+func (t *Template) ExecuteGzipIf(w io.Writer, minBytes int, ctx interface{}, globs ...string) (gzipped bool, err error) {
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, ctx, globs...); err != nil {
+		return
+	}
+	if buf.Len() <= minBytes {
+		_, err = buf.WriteTo(w)
+		return
+	}
+
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	if _, err = buf.WriteTo(zw); err != nil {
+		return
+	}
+	if err = zw.Close(); err != nil {
+		return
+	}
+	if _, err = out.WriteTo(w); err != nil {
+		return
+	}
+	return true, nil
+}
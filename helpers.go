@@ -0,0 +1,320 @@
+package tmplmgr
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//WithMarkdown attaches a markdown function to the template that renders its
+//argument to HTML with renderer, so {% markdown .Body %} includes it in the
+//page unescaped. Errors from renderer fail the Execute. In Production mode, of
+//the package or set with SetMode, the output is cached by a hash of the input,
+//so renderer runs once per distinct document. Up to markdownCacheSize outputs
+//are kept; once full, an arbitrary one is forgotten for every new one.
+func (t *Template) WithMarkdown(renderer func(string) (template.HTML, error)) *Template {
+	m := &markdownCache{render: renderer, mode: t.compileMode, out: map[[sha256.Size]byte]template.HTML{}}
+	return t.Call("markdown", m.markdown)
+}
+
+//markdownCacheSize is the most outputs a markdownCache keeps.
+const markdownCacheSize = 1000
+
+//markdownCache caches the output of a markdown renderer.
+type markdownCache struct {
+	render func(string) (template.HTML, error)
+	mode   func() Mode //mode of the template, called while it executes
+
+	mu  sync.Mutex
+	out map[[sha256.Size]byte]template.HTML
+}
+
+//markdown is the function attached by WithMarkdown.
+func (m *markdownCache) markdown(src string) (out template.HTML, err error) {
+	if m.mode() == Development {
+		return m.render(src)
+	}
+
+	sum := sha256.Sum256([]byte(src))
+	m.mu.Lock()
+	out, ex := m.out[sum]
+	m.mu.Unlock()
+	if ex {
+		return
+	}
+
+	if out, err = m.render(src); err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	for k := range m.out {
+		if len(m.out) < markdownCacheSize {
+			break
+		}
+		delete(m.out, k)
+	}
+	m.out[sum] = out
+	m.mu.Unlock()
+	return
+}
+
+//WithRangeHelpers attaches the seq, until and dict functions to the template:
+//
+//	seq a b          the integers from a to b inclusive, counting down if b < a
+//	until n          the integers from 0 to n-1
+//	dict k1 v1 ...   a map[string]interface{} of the key/value pairs
+//
+//The sizes come from templates and so from their contexts, so seq and until
+//fail the Execute rather than make more than maxRange integers. They are not
+//attached by default so templates are free to define their own.
+func (t *Template) WithRangeHelpers() *Template {
+	return t.Call("seq", seq).Call("until", until).Call("dict", dict)
+}
+
+//maxRange is the most integers seq and until make.
+const maxRange = 1 << 20
+
+func seq(a, b int) ([]int, error) {
+	//the distance is taken unsigned, as b-a can overflow an int
+	step, dist := 1, uint64(b)-uint64(a)
+	if b < a {
+		step, dist = -1, uint64(a)-uint64(b)
+	}
+	if dist >= maxRange {
+		return nil, fmt.Errorf("seq: range %d to %d exceeds %d integers", a, b, maxRange)
+	}
+	out := make([]int, 0, dist+1)
+	for i := a; ; i += step {
+		out = append(out, i)
+		if i == b {
+			return out, nil
+		}
+	}
+}
+
+func until(n int) ([]int, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("until: negative count %d", n)
+	}
+	if n > maxRange {
+		return nil, fmt.Errorf("until: count %d exceeds %d", n, maxRange)
+	}
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out, nil
+}
+
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: odd number of arguments %d", len(pairs))
+	}
+	out := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v at argument %d is a %T, not a string", pairs[i], i, pairs[i])
+		}
+		out[key] = pairs[i+1]
+	}
+	return out, nil
+}
+
+//WithSortHelpers attaches the sortBy function to the template:
+//
+//	sortBy items f1 f2 ...   a sorted copy of the slice by the fields
+//
+//Items may be structs, pointers to structs or string keyed maps, and they are
+//ordered by the first field, then by the next for equal values, keeping the
+//original order of equal items. Fields may be strings, numbers, bools or
+//time.Times. It is not attached by default so templates are free to define
+//their own.
+func (t *Template) WithSortHelpers() *Template {
+	return t.Call("sortBy", sortBy)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func sortBy(items interface{}, fields ...string) (interface{}, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("sortBy: can't sort a %T", items)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("sortBy: no fields to sort by")
+	}
+
+	//look every key up front so sorting can't fail halfway
+	keys := make([][]reflect.Value, v.Len())
+	for i := range keys {
+		keys[i] = make([]reflect.Value, len(fields))
+		for j, field := range fields {
+			key, err := sortKey(v.Index(i), field)
+			if err != nil {
+				return nil, fmt.Errorf("sortBy: item %d: %v", i, err)
+			}
+			if i > 0 && !sameKeyClass(keys[0][j], key) {
+				return nil, fmt.Errorf("sortBy: item %d: field %s is a %v, not a %v", i, field, key.Type(), keys[0][j].Type())
+			}
+			keys[i][j] = key
+		}
+	}
+
+	order := make([]int, v.Len())
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ka, kb := keys[order[a]], keys[order[b]]
+		for j := range fields {
+			if c := compareKeys(ka[j], kb[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+	for i, idx := range order {
+		out.Index(i).Set(v.Index(idx))
+	}
+	return out.Interface(), nil
+}
+
+//sortKey returns the named field of the item, which must be sortable.
+func sortKey(item reflect.Value, field string) (key reflect.Value, err error) {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		if item.IsNil() {
+			return key, fmt.Errorf("nil item")
+		}
+		item = item.Elem()
+	}
+
+	switch item.Kind() {
+	case reflect.Struct:
+		key = item.FieldByName(field)
+	case reflect.Map:
+		if item.Type().Key().Kind() == reflect.String {
+			key = item.MapIndex(reflect.ValueOf(field).Convert(item.Type().Key()))
+		}
+	default:
+		return key, fmt.Errorf("a %v has no fields", item.Type())
+	}
+	if !key.IsValid() {
+		return key, fmt.Errorf("no field %s", field)
+	}
+	for key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+
+	switch key.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return key, nil
+	}
+	if key.Type() == timeType {
+		return key, nil
+	}
+	return key, fmt.Errorf("field %s is a %v, which can't be sorted", field, key.Type())
+}
+
+//sameKeyClass reports if keys of the same field of two items can be compared.
+func sameKeyClass(a, b reflect.Value) bool {
+	if a.Type() == timeType || b.Type() == timeType {
+		return a.Type() == b.Type()
+	}
+	return keyClass(a.Kind()) == keyClass(b.Kind())
+}
+
+//keyClass groups the kinds that compare with each other.
+func keyClass(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return k
+}
+
+//compareKeys returns -1, 0 or 1 as a sorts before, with or after b.
+func compareKeys(a, b reflect.Value) int {
+	if a.Type() == timeType {
+		ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1
+		case ta.After(tb):
+			return 1
+		}
+		return 0
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case !a.Bool():
+			return -1
+		}
+		return 1
+	}
+
+	//compare integers exactly when neither side needs a float
+	if ia, ok := integer(a); ok {
+		if ib, ok := integer(b); ok {
+			switch {
+			case ia < ib:
+				return -1
+			case ia > ib:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fa, fb := number(a), number(b)
+	switch {
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	}
+	return 0
+}
+
+//integer returns the value of a signed integer, or an unsigned one that fits.
+func integer(v reflect.Value) (int64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := v.Uint(); u <= math.MaxInt64 {
+			return int64(u), true
+		}
+	}
+	return 0, false
+}
+
+//number returns the numeric value as a float64.
+func number(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
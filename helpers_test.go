@@ -0,0 +1,203 @@
+package tmplmgr
+
+import (
+	"crypto/sha256"
+	"html/template"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSeq(t *testing.T) {
+	cases := []struct {
+		a, b int
+		want []int
+	}{
+		{0, 3, []int{0, 1, 2, 3}},
+		{3, 0, []int{3, 2, 1, 0}},
+		{-1, 1, []int{-1, 0, 1}},
+		{5, 5, []int{5}},
+		{math.MaxInt - 1, math.MaxInt, []int{math.MaxInt - 1, math.MaxInt}},
+		{math.MinInt + 1, math.MinInt, []int{math.MinInt + 1, math.MinInt}},
+	}
+	for _, c := range cases {
+		got, err := seq(c.a, c.b)
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("seq(%d, %d) = %v, %v, want %v", c.a, c.b, got, err, c.want)
+		}
+	}
+
+	for _, c := range [][2]int{
+		{0, -math.MaxInt},
+		{math.MinInt, math.MaxInt},
+		{math.MaxInt, math.MinInt},
+		{0, maxRange},
+	} {
+		if _, err := seq(c[0], c[1]); err == nil {
+			t.Errorf("seq(%d, %d) did not fail", c[0], c[1])
+		}
+	}
+	if got, err := seq(1, maxRange); err != nil || len(got) != maxRange {
+		t.Errorf("seq(1, maxRange) = %d integers, %v", len(got), err)
+	}
+}
+
+func TestUntil(t *testing.T) {
+	if got, err := until(3); err != nil || !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("until(3) = %v, %v", got, err)
+	}
+	if got, err := until(0); err != nil || len(got) != 0 {
+		t.Errorf("until(0) = %v, %v", got, err)
+	}
+	for _, n := range []int{-1, maxRange + 1, 1000000000, math.MaxInt} {
+		if _, err := until(n); err == nil {
+			t.Errorf("until(%d) did not fail", n)
+		}
+	}
+}
+
+func TestRangeHelpersExecute(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% range seq .A .B %}{% . %}{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).WithRangeHelpers()
+	if out, err := tm.ExecuteString(map[string]int{"A": 1, "B": 3}); err != nil || out != "123" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if _, err := tm.ExecuteString(map[string]int{"A": 0, "B": -math.MaxInt}); err == nil {
+		t.Fatal("want the range to fail the Execute")
+	}
+}
+
+func TestMarkdownTemplateMode(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% markdown . %}`})
+	renders := 0
+	tm := Parse(filepath.Join(dir, "base.tmpl")).WithMarkdown(func(src string) (template.HTML, error) {
+		renders++
+		return template.HTML("<p>" + src + "</p>"), nil
+	})
+	for i := 0; i < 2; i++ {
+		if out, err := tm.ExecuteString("x"); err != nil || out != "<p>x</p>" {
+			t.Fatalf("got %q, %v", out, err)
+		}
+	}
+	if renders != 1 {
+		t.Fatalf("Production rendered %d times", renders)
+	}
+
+	tm.SetMode(Development)
+	tm.ExecuteString("x")
+	tm.ExecuteString("x")
+	if renders != 3 {
+		t.Fatalf("Development rendered %d times in all, want 3", renders)
+	}
+}
+
+func TestMarkdownCacheSize(t *testing.T) {
+	renders := 0
+	m := &markdownCache{
+		render: func(src string) (template.HTML, error) { renders++; return template.HTML(src), nil },
+		mode:   func() Mode { return Production },
+		out:    map[[sha256.Size]byte]template.HTML{},
+	}
+	for i := 0; i < markdownCacheSize+10; i++ {
+		if _, err := m.markdown(strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(m.out) != markdownCacheSize {
+		t.Errorf("cache holds %d outputs, want %d", len(m.out), markdownCacheSize)
+	}
+
+	//the newest output is still cached
+	renders = 0
+	if out, err := m.markdown(strconv.Itoa(markdownCacheSize + 9)); err != nil || renders != 0 || out != template.HTML(strconv.Itoa(markdownCacheSize+9)) {
+		t.Errorf("got %q, %v after %d renders", out, err, renders)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	type person struct {
+		Name string
+		Age  int
+		Born time.Time
+	}
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	people := []person{
+		{"cy", 30, day(3)},
+		{"al", 40, day(1)},
+		{"bo", 30, day(2)},
+		{"al", 20, day(4)},
+	}
+	names := func(v interface{}) (out []string) {
+		for _, p := range v.([]person) {
+			out = append(out, p.Name+strconv.Itoa(p.Age))
+		}
+		return
+	}
+
+	cases := []struct {
+		fields []string
+		want   []string
+	}{
+		{[]string{"Name"}, []string{"al40", "al20", "bo30", "cy30"}},
+		{[]string{"Age"}, []string{"al20", "cy30", "bo30", "al40"}},
+		{[]string{"Born"}, []string{"al40", "bo30", "cy30", "al20"}},
+		{[]string{"Name", "Age"}, []string{"al20", "al40", "bo30", "cy30"}},
+		{[]string{"Age", "Name"}, []string{"al20", "bo30", "cy30", "al40"}},
+	}
+	for _, c := range cases {
+		out, err := sortBy(people, c.fields...)
+		if err != nil {
+			t.Errorf("%v: %v", c.fields, err)
+			continue
+		}
+		if got := names(out); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v: got %v, want %v", c.fields, got, c.want)
+		}
+	}
+	if people[0].Name != "cy" {
+		t.Error("sortBy modified its input")
+	}
+
+	maps := []map[string]interface{}{{"K": 2}, {"K": 1.5}, {"K": 1}}
+	if out, err := sortBy(maps, "K"); err != nil || out.([]map[string]interface{})[0]["K"] != 1 {
+		t.Errorf("maps: got %v, %v", out, err)
+	}
+
+	for _, c := range []struct {
+		items  interface{}
+		fields []string
+	}{
+		{"people", []string{"Name"}},
+		{people, nil},
+		{people, []string{"Missing"}},
+		{[]*person{{Name: "a"}, nil}, []string{"Name"}},
+		{[]map[string]interface{}{{"K": 1}, {"K": "a"}}, []string{"K"}},
+		{[]struct{ F []int }{{}}, []string{"F"}},
+	} {
+		if _, err := sortBy(c.items, c.fields...); err == nil {
+			t.Errorf("sortBy(%v, %v) did not fail", c.items, c.fields)
+		}
+	}
+}
+
+func TestWithSortHelpers(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% range sortBy . "Age" %}{% .Name %} {% end %}`,
+	})
+	items := []struct {
+		Name string
+		Age  int
+	}{{"b", 2}, {"a", 1}}
+	out, err := Parse(filepath.Join(dir, "base.tmpl")).WithSortHelpers().ExecuteString(items)
+	if err != nil || out != "a b " {
+		t.Errorf("got %q, %v", out, err)
+	}
+}
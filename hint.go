@@ -0,0 +1,40 @@
+package tmplmgr
+
+//HintSize sets the output size ExecuteAtomic, ExecuteString, ExecuteBuilder
+//and ExecuteBytes expect, allocating their buffers at that size up front
+//instead of growing them from nothing. Once set, the hint follows a moving
+//average of the sizes actually rendered, never going below n, so it grows on
+//its own for pages that turn out larger. Zero, the default, or less turns it
+//off.
+func (t *Template) HintSize(n int) *Template {
+	if n < 0 {
+		n = 0
+	}
+	t.hint_min.Store(int64(n))
+	t.hint_avg.Store(int64(n))
+	return t
+}
+
+//sizeHint returns the size to allocate a render buffer at, or 0.
+func (t *Template) sizeHint() int {
+	return int(t.hint_avg.Load())
+}
+
+//recordSize moves the size hint an eighth of the way toward n, keeping it at
+//or above the size set with HintSize.
+func (t *Template) recordSize(n int) {
+	min := t.hint_min.Load()
+	if min <= 0 {
+		return
+	}
+	for {
+		old := t.hint_avg.Load()
+		avg := old + (int64(n)-old)/8
+		if avg < min {
+			avg = min
+		}
+		if avg == old || t.hint_avg.CompareAndSwap(old, avg) {
+			return
+		}
+	}
+}
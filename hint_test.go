@@ -0,0 +1,62 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHintSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% . %}`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).HintSize(100)
+
+	big := strings.Repeat("a", 10000)
+	for i := 0; i < 50; i++ {
+		if out, err := tm.ExecuteString(big); err != nil || len(out) != len(big) {
+			t.Fatalf("got %d bytes, %v", len(out), err)
+		}
+	}
+	if h := tm.sizeHint(); h < 9000 || h > 10000 {
+		t.Errorf("hint after large renders = %d", h)
+	}
+	for i := 0; i < 200; i++ {
+		tm.ExecuteBytes("x")
+	}
+	if h := tm.sizeHint(); h != 100 {
+		t.Errorf("hint after small renders = %d, want the minimum 100", h)
+	}
+}
+
+func TestHintSizeNegative(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% . %}`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).HintSize(-1)
+	if h := tm.sizeHint(); h != 0 {
+		t.Fatalf("hint = %d, want off", h)
+	}
+	if out, err := tm.ExecuteString("x"); err != nil || out != "x" {
+		t.Fatalf("ExecuteString = %q, %v", out, err)
+	}
+	if out, err := tm.ExecuteBytes("x"); err != nil || string(out) != "x" {
+		t.Fatalf("ExecuteBytes = %q, %v", out, err)
+	}
+}
+
+func BenchmarkExecuteStringNoHint(b *testing.B) { benchmarkHint(b, 0) }
+func BenchmarkExecuteStringHint(b *testing.B)   { benchmarkHint(b, 1) }
+
+//benchmarkHint renders a page of about 30kB with the hint set to hint.
+func benchmarkHint(b *testing.B, hint int) {
+	dir := b.TempDir()
+	writeFiles(b, dir, map[string]string{"base.tmpl": `{% range . %}<li>{% . %}</li>{% end %}`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).HintSize(hint)
+	items := make([]int, 2000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := tm.ExecuteString(items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
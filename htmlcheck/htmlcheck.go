@@ -0,0 +1,96 @@
+//Package htmlcheck validates the output of tmplmgr templates with an HTML
+//tokenizer, to catch markup broken by a buggy conditional in CI. It lives in
+//its own package so tmplmgr itself does not depend on golang.org/x/net/html.
+package htmlcheck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/go-goods/tmplmgr"
+	"golang.org/x/net/html"
+)
+
+//ValidateHTML executes the template with the context and globs and checks the
+//output is well formed, returning an error describing the first problem, such
+//as an element that is never closed or an end tag with no element to close.
+//Void elements and elements whose end tag HTML allows to be left out are not
+//required to be closed.
+func ValidateHTML(t *tmplmgr.Template, ctx interface{}, globs ...string) (err error) {
+	out, err := t.ExecuteBytes(ctx, globs...)
+	if err != nil {
+		return
+	}
+	return Check(out)
+}
+
+//Check checks the HTML in b is well formed, like ValidateHTML.
+func Check(b []byte) error {
+	type open struct {
+		name string
+		line int
+	}
+
+	var stack []open
+	line := 1
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		tt := z.Next()
+		at := line
+		line += bytes.Count(z.Raw(), []byte("\n"))
+
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return fmt.Errorf("line %d: %v", at, z.Err())
+			}
+			for i := len(stack) - 1; i >= 0; i-- {
+				if !optionalEnd[stack[i].name] {
+					return fmt.Errorf("line %d: <%s> is never closed", stack[i].line, stack[i].name)
+				}
+			}
+			return nil
+
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if !void[string(name)] {
+				stack = append(stack, open{string(name), at})
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			i := len(stack) - 1
+			for i >= 0 && stack[i].name != string(name) {
+				i--
+			}
+			if i < 0 {
+				if void[string(name)] {
+					continue
+				}
+				return fmt.Errorf("line %d: </%s> closes no open element", at, name)
+			}
+			for _, o := range stack[i+1:] {
+				if !optionalEnd[o.name] {
+					return fmt.Errorf("line %d: <%s> from line %d is not closed before </%s>", at, o.name, o.line, name)
+				}
+			}
+			stack = stack[:i]
+		}
+	}
+}
+
+//void holds the elements that have no end tag.
+var void = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+//optionalEnd holds the elements whose end tag may be left out.
+var optionalEnd = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true,
+	"dt": true, "dd": true, "option": true, "optgroup": true, "colgroup": true,
+	"caption": true, "thead": true, "tbody": true, "tfoot": true, "tr": true,
+	"td": true, "th": true, "rb": true, "rt": true, "rtc": true, "rp": true,
+}
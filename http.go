@@ -0,0 +1,174 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//ExecuteHTTP is like ExecuteAtomic but also returns suggested HTTP cache
+//headers for the output: the ContentType, Last-Modified from the newest of the
+//template's files, an ETag from the rendered content, and a Cache-Control of
+//no-cache in Development mode or a short public max-age in Production mode.
+//The headers are only suggestions, it is up to the caller to set them on the
+//response.
+func (t *Template) ExecuteHTTP(w io.Writer, ctx interface{}, globs ...string) (headers map[string]string, err error) {
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, ctx, globs...); err != nil {
+		return
+	}
+	if headers, err = t.httpHeaders(buf.Bytes(), globs); err != nil {
+		return
+	}
+
+	if _, err = buf.WriteTo(w); err != nil {
+		headers = nil
+	}
+	return
+}
+
+//ExecuteRequest renders the template for the request r and writes the response
+//to w. The render is canceled with the request's context, as by
+//ExecuteContext, and is atomic: on error nothing is written, so the caller can
+//still respond with an error page. Otherwise the headers ExecuteHTTP suggests
+//are set, and if the request's If-None-Match or If-Modified-Since show the
+//client already has the output, a 304 Not Modified is sent without a body.
+func (t *Template) ExecuteRequest(w http.ResponseWriter, r *http.Request, ctx interface{}, globs ...string) (err error) {
+	var buf bytes.Buffer
+	if err = t.ExecuteContext(r.Context(), &buf, ctx, globs...); err != nil {
+		return
+	}
+	headers, err := t.httpHeaders(buf.Bytes(), globs)
+	if err != nil {
+		return
+	}
+
+	h := w.Header()
+	for key, value := range headers {
+		h.Set(key, value)
+	}
+	if notModified(r, headers) {
+		h.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	_, err = buf.WriteTo(w)
+	return
+}
+
+//notModified reports if the conditional headers of the request match the
+//response headers, following the precedence of RFC 7232: If-Modified-Since is
+//only looked at without an If-None-Match.
+func notModified(r *http.Request, headers map[string]string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == headers["ETag"] {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || headers["Last-Modified"] == "" {
+		return false
+	}
+	modified, err := http.ParseTime(headers["Last-Modified"])
+	return err == nil && !modified.After(since)
+}
+
+//httpHeaders returns the headers ExecuteHTTP suggests for the output of an
+//Execute with the globs.
+func (t *Template) httpHeaders(out []byte, globs []string) (headers map[string]string, err error) {
+	modified, err := t.lastModified(globs)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(out)
+
+	headers = map[string]string{
+		"Content-Type":  t.ContentType(),
+		"ETag":          `"` + hex.EncodeToString(sum[:])[:16] + `"`,
+		"Cache-Control": "public, max-age=300",
+	}
+	t.compile_lock.RLock()
+	mode := t.compileMode()
+	t.compile_lock.RUnlock()
+	if mode == Development {
+		headers["Cache-Control"] = "no-cache"
+	}
+	if !modified.IsZero() {
+		headers["Last-Modified"] = modified.UTC().Format(http.TimeFormat)
+	}
+	return
+}
+
+//lastModified returns the newest modification time of the files an Execute
+//with the globs reads, ignoring files that can't be found.
+func (t *Template) lastModified(globs []string) (modified time.Time, err error) {
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	files, err := t.matchFiles(t.withDefaultGlobs(globs))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if info, err := t.stat(file); err == nil && info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+	}
+	return
+}
+
+//contentTypes are the content types of the usual template extensions.
+var contentTypes = map[string]string{
+	".html": "text/html; charset=utf-8",
+	".htm":  "text/html; charset=utf-8",
+	".txt":  "text/plain; charset=utf-8",
+	".xml":  "application/xml; charset=utf-8",
+	".json": "application/json",
+	".css":  "text/css; charset=utf-8",
+	".js":   "text/javascript; charset=utf-8",
+	".svg":  "image/svg+xml",
+}
+
+//ContentType returns the content type of the template's output, as set with
+//SetContentType or else derived from the extension of the base file. Unknown
+//extensions give text/html, which is what html/template escapes for.
+func (t *Template) ContentType() string {
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	if t.content_type != "" {
+		return t.content_type
+	}
+	ext := strings.ToLower(filepath.Ext(t.base))
+	if typ, ok := contentTypes[ext]; ok {
+		return typ
+	}
+	if typ := mime.TypeByExtension(ext); typ != "" {
+		return typ
+	}
+	return contentTypes[".html"]
+}
+
+//SetContentType overrides the content type ContentType reports.
+func (t *Template) SetContentType(typ string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.content_type = typ
+	return t
+}
@@ -0,0 +1,49 @@
+package tmplmgr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecuteRequestETag(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `<p>{% . %}</p>`})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+
+	get := func(ctx interface{}, match string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if match != "" {
+			r.Header.Set("If-None-Match", match)
+		}
+		w := httptest.NewRecorder()
+		if err := tm.ExecuteRequest(w, r, ctx); err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	w := get("a", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "<p>a</p>" || etag == "" {
+		t.Fatalf("got %d %q, ETag %q", w.Code, w.Body.String(), etag)
+	}
+
+	for _, match := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+		w = get("a", match)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: got %d %q", match, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != "" {
+			t.Errorf("If-None-Match %s: 304 has a Content-Type", match)
+		}
+	}
+
+	//a changed output no longer matches
+	w = get("b", etag)
+	if w.Code != http.StatusOK || w.Body.String() != "<p>b</p>" || w.Header().Get("ETag") == etag {
+		t.Errorf("changed output: got %d %q, ETag %q", w.Code, w.Body.String(), w.Header().Get("ETag"))
+	}
+}
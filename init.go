@@ -0,0 +1,98 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+//errorType is the type of the error interface.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+//Dirty reports if the template has changes that have not been compiled yet.
+func (t *Template) Dirty() bool {
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	return t.dirty || t.t == nil
+}
+
+//ResetCompiled drops every cached glob set and marks the template Dirty, so the
+//next Compile or Execute compiles it again from scratch. Unlike Call and the
+//other options, which also drop the cached sets, it changes no configuration.
+//The parse trees kept for unchanged files are dropped too, as it is meant for
+//measuring cold compiles repeatedly. The compiled template is kept until then,
+//so Executes that already compiled keep rendering with it.
+func (t *Template) ResetCompiled() {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.dirty = true
+	t.clearCache()
+	if t.trees != nil {
+		t.trees = &treeCache{files: map[string]fileTrees{}}
+	}
+}
+
+//Warm compiles the template if needed and builds the cached template set for
+//each of the glob sets, with the DefaultGlobs, so the first Execute with them
+//doesn't pay for it. It returns the first error encountered.
+func (t *Template) Warm(globSets ...[]string) (err error) {
+	if t.Dirty() {
+		if err = t.Compile(); err != nil {
+			return
+		}
+	}
+
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	for _, globs := range globSets {
+		if _, _, err = t.getCachedGlobs(t.withDefaultGlobs(globs), t.development()); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//Initialize finishes setting up the template: it checks every attached
+//function can be called from a template, compiles the template, and warms the
+//glob sets. It returns the first error encountered, and afterward the template
+//is ready to serve and not Dirty.
+func (t *Template) Initialize(globSets ...[]string) (err error) {
+	if err = t.validateFuncs(); err != nil {
+		return
+	}
+	if err = t.Compile(); err != nil {
+		return
+	}
+	return t.Warm(globSets...)
+}
+
+//validateFuncs returns an error for the first attached function, in name
+//order, that templates can't call: it must be a function returning one value,
+//or a value and an error.
+func (t *Template) validateFuncs() error {
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	names := make([]string, 0, len(t.funcs))
+	for name := range t.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		typ := reflect.TypeOf(t.funcs[name])
+		if typ == nil || typ.Kind() != reflect.Func {
+			return fmt.Errorf("%s: func %s is a %v, not a function", t.base, name, typ)
+		}
+		switch {
+		case typ.NumOut() == 1:
+		case typ.NumOut() == 2 && typ.Out(1) == errorType:
+		default:
+			return fmt.Errorf("%s: func %s must return one value, or a value and an error", t.base, name)
+		}
+	}
+	return nil
+}
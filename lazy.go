@@ -0,0 +1,126 @@
+package tmplmgr
+
+import (
+	"html/template"
+	"regexp"
+	"strconv"
+	"text/template/parse"
+)
+
+//LazyBlocks attaches the files matching the globs without parsing them up
+//front. Every compile scans them for the names they define, which is much
+//cheaper than parsing, and only parses the files defining a template that is
+//invoked but not defined by the rest of the set, and so on for what those
+//invoke. A page using a few partials of a large library then only pays for
+//those. If several files define a name the first to define it is parsed, and
+//like any file parsed later, it replaces the other templates it defines.
+func (t *Template) LazyBlocks(globs ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.lazy = append(t.lazy, globs...)
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//lazyFiles returns the files matching the lazy globs. The caller must hold at
+//least the read lock.
+func (t *Template) lazyFiles() (files []string, err error) {
+	for _, glob := range t.lazy {
+		var matches []string
+		if matches, err = t.globFiles(glob); err != nil {
+			return
+		}
+		files = append(files, matches...)
+	}
+	return
+}
+
+//lazyIndex returns the file defining every name defined in the lazy files.
+//The files are scanned once per compile, for the compiled set and every set
+//of globs passed to Execute. The caller must hold at least the read lock.
+func (t *Template) lazyIndex() (index map[string]string, err error) {
+	t.cache_lock.Lock()
+	index = t.lazy_index
+	t.cache_lock.Unlock()
+	if index != nil {
+		return
+	}
+
+	files, err := t.lazyFiles()
+	if err != nil {
+		return
+	}
+	index = map[string]string{}
+	for _, file := range files {
+		var names []string
+		if names, err = t.defines(file); err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if _, ok := index[name]; !ok {
+				index[name] = file
+			}
+		}
+	}
+
+	t.cache_lock.Lock()
+	t.lazy_index = index
+	t.cache_lock.Unlock()
+	return
+}
+
+//defines returns the names the file defines, found by scanning it for define
+//and block actions rather than parsing it. Names may be quoted or raw strings.
+func (t *Template) defines(file string) (names []string, err error) {
+	data, err := t.readFile(file)
+	if err != nil {
+		return
+	}
+
+	left, _ := t.delims()
+	define := regexp.MustCompile(regexp.QuoteMeta(left) + `-?\s*(?:define|block)\s+("(?:[^"\\\n]|\\.)*"|` + "`[^`]*`)")
+	for _, m := range define.FindAllSubmatch(data, -1) {
+		if name, err := strconv.Unquote(string(m[1])); err == nil {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+//loadLazy parses the lazy files defining the templates invoked but not defined
+//in tmpl into it, until every invoked template is defined or not defined by
+//any lazy file. The caller must hold at least the read lock.
+func (t *Template) loadLazy(tmpl *template.Template) (err error) {
+	index, err := t.lazyIndex()
+	if err != nil || len(index) == 0 {
+		return
+	}
+
+	parsed := map[string]bool{}
+	for {
+		var files []string
+		for _, x := range tmpl.Templates() {
+			if x.Tree == nil {
+				continue
+			}
+			walk(x.Tree.Root, func(node parse.Node) {
+				n, ok := node.(*parse.TemplateNode)
+				if !ok || tmpl.Lookup(n.Name) != nil {
+					return
+				}
+				if file, ok := index[n.Name]; ok && !parsed[file] {
+					parsed[file] = true
+					files = append(files, file)
+				}
+			})
+		}
+		if len(files) == 0 {
+			return
+		}
+		if _, err = t.parseCached(tmpl, files...); err != nil {
+			return
+		}
+	}
+}
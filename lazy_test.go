@@ -0,0 +1,45 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLazyBlocksIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl":       `{% template "quoted" %}|{% template "raw" %}|{% template "block" %}`,
+		"lazy/q.tmpl":     `{% define "quoted" %}q{% end %}`,
+		"lazy/r.tmpl":     "{%- define `raw` %}r{% end %}",
+		"lazy/b.tmpl":     `{% block "block" . %}b{% end %}`,
+		"lazy/e.tmpl":     `{% define "esc\"aped" %}e{% end %}`,
+		"exec/extra.tmpl": `{% define "extra" %}x{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).LazyBlocks(filepath.Join(dir, "lazy", "*.tmpl"))
+	if out, err := tm.ExecuteString(nil); err != nil || out != "q|r|b" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if _, err := tm.ExecuteString(nil, filepath.Join(dir, "exec", "*.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"quoted":   filepath.Join(dir, "lazy", "q.tmpl"),
+		"raw":      filepath.Join(dir, "lazy", "r.tmpl"),
+		"block":    filepath.Join(dir, "lazy", "b.tmpl"),
+		`esc"aped`: filepath.Join(dir, "lazy", "e.tmpl"),
+	}
+	if !reflect.DeepEqual(tm.lazy_index, want) {
+		t.Fatalf("index %v, want %v", tm.lazy_index, want)
+	}
+
+	//another set of globs passed to Execute reuses the index
+	index := tm.lazy_index
+	if _, err := tm.ExecuteString(nil, filepath.Join(dir, "exec", "extra.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(tm.lazy_index).Pointer() != reflect.ValueOf(index).Pointer() {
+		t.Fatal("the lazy files were scanned again")
+	}
+}
@@ -0,0 +1,81 @@
+package tmplmgr
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+//ManifestErrors holds every error WarmFromManifest ran into.
+type ManifestErrors = Errors
+
+//WarmFromManifest warms the glob sets listed in the manifest file for the
+//registered templates, so every page served pays its compile costs at startup.
+//The manifest is either a JSON object mapping template names to lists of glob
+//sets, or lines of a template name followed by the globs of one set separated
+//by spaces, where blank lines and lines starting with # are skipped. A name on
+//its own line warms the set with no globs. Every entry is warmed even if some
+//fail, and the failures are returned together as ManifestErrors.
+func WarmFromManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sets, order, err := parseManifest(data)
+	if err != nil {
+		return fmt.Errorf("manifest %s: %v", path, err)
+	}
+
+	var errs ManifestErrors
+	for _, name := range order {
+		t, ok := Get(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("no template registered as %q", name))
+			continue
+		}
+		for _, globs := range sets[name] {
+			if err := t.Warm(globs); err != nil {
+				errs = append(errs, fmt.Errorf("%s %v: %v", name, globs, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//parseManifest returns the glob sets listed for each template name and the
+//names in the order they first appear.
+func parseManifest(data []byte) (sets map[string][][]string, order []string, err error) {
+	sets = map[string][][]string{}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if err = json.Unmarshal(data, &sets); err != nil {
+			return
+		}
+		for name := range sets {
+			order = append(order, name)
+		}
+		sort.Strings(order)
+		return
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		name := fields[0]
+		if _, ex := sets[name]; !ex {
+			order = append(order, name)
+		}
+		sets[name] = append(sets[name], fields[1:])
+	}
+	err = scanner.Err()
+	return
+}
@@ -0,0 +1,110 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+//defaultMemoSize is the number of outputs ExecuteMemo keeps unless MemoSize
+//says otherwise.
+const defaultMemoSize = 1000
+
+//ExecuteMemo executes the named template like ExecuteFragments with a single
+//name and writes its output to w, remembering the output by the name, the
+//globs and a hash of the context so an identical context renders only once.
+//The context is hashed by its JSON encoding, so only its exported data counts:
+//contexts whose methods depend on unexported fields must not be memoized, and
+//contexts that can't be encoded are rendered every time. The output is
+//remembered before the output filters, ErrorOnNoValue, PrettyPrint and
+//LineEndings, which run on every call like they do for Execute. Remembered
+//outputs are dropped whenever the template is compiled again.
+func (t *Template) ExecuteMemo(w io.Writer, name string, ctx interface{}, globs ...string) (err error) {
+	var res Result
+	err = t.render(globs, &res, func(tmpl *template.Template) (err error) {
+		if ctx, err = t.prepare(ctx); err != nil {
+			return
+		}
+
+		key, ok := memoKey(name, globs, ctx)
+		if ok {
+			t.cache_lock.Lock()
+			out, hit := t.memo[key]
+			t.cache_lock.Unlock()
+			if hit {
+				return t.writeMemo(w, out)
+			}
+		}
+
+		var buf bytes.Buffer
+		if err = tmpl.ExecuteTemplate(newCountWriter(&buf, t.max_output), name, ctx); err != nil {
+			return
+		}
+		if ok {
+			t.remember(key, buf.Bytes())
+		}
+		return t.writeMemo(w, buf.Bytes())
+	})
+	return
+}
+
+//writeMemo writes the output of ExecuteMemo to w, through the filters if there
+//are any. Filters may change the output in place, so they get a copy of the
+//remembered one. The caller must hold the read lock.
+func (t *Template) writeMemo(w io.Writer, out []byte) (err error) {
+	if t.filtering() {
+		if out, err = t.filter(append([]byte(nil), out...)); err != nil {
+			return
+		}
+	}
+	_, err = newCountWriter(w, 0).Write(out)
+	return
+}
+
+//MemoSize sets how many outputs ExecuteMemo remembers. Once full, an arbitrary
+//output is forgotten for every new one.
+func (t *Template) MemoSize(n int) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.memo_size = n
+	return t
+}
+
+//memoKey returns the key ExecuteMemo remembers the output under, and false if
+//the context can't be hashed.
+func memoKey(name string, globs []string, ctx interface{}) (string, bool) {
+	data, err := json.Marshal(ctx)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s\x00%s\x00%T\x00%s", name, globsKey(globs), ctx, hex.EncodeToString(sum[:])), true
+}
+
+//remember stores a copy of the output under the key, making room if the memo
+//is full. The caller must hold the read lock.
+func (t *Template) remember(key string, out []byte) {
+	size := t.memo_size
+	if size <= 0 {
+		size = defaultMemoSize
+	}
+
+	t.cache_lock.Lock()
+	defer t.cache_lock.Unlock()
+
+	if t.memo == nil {
+		t.memo = map[string][]byte{}
+	}
+	for k := range t.memo {
+		if len(t.memo) < size {
+			break
+		}
+		delete(t.memo, k)
+	}
+	t.memo[key] = append([]byte(nil), out...)
+}
@@ -0,0 +1,88 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoKey(t *testing.T) {
+	type ctx struct{ A int }
+	type other struct{ A int }
+	base, ok := memoKey("a", []string{"x"}, ctx{1})
+	if !ok {
+		t.Fatal("context not hashed")
+	}
+	if again, _ := memoKey("a", []string{"x"}, ctx{1}); again != base {
+		t.Error("equal contexts got different keys")
+	}
+	for _, c := range []struct {
+		name  string
+		globs []string
+		ctx   interface{}
+	}{
+		{"b", []string{"x"}, ctx{1}},
+		{"a", []string{"y"}, ctx{1}},
+		{"a", []string{"x", "y"}, ctx{1}},
+		{"a", []string{"x,y"}, ctx{1}},
+		{"a", []string{"x"}, ctx{2}},
+		{"a", []string{"x"}, other{1}},
+	} {
+		if key, _ := memoKey(c.name, c.globs, c.ctx); key == base {
+			t.Errorf("%q %q %#v shares the key", c.name, c.globs, c.ctx)
+		}
+	}
+	if _, ok := memoKey("a", nil, make(chan int)); ok {
+		t.Error("a context that can't be encoded was hashed")
+	}
+}
+
+func TestMemoSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% define "row" %}<td>{% . %}</td>{% end %}`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).MemoSize(2)
+	for i := 0; i < 5; i++ {
+		var buf bytes.Buffer
+		if err := tm.ExecuteMemo(&buf, "row", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tm.cache_lock.Lock()
+	n := len(tm.memo)
+	tm.cache_lock.Unlock()
+	if n != 2 {
+		t.Errorf("memo holds %d outputs, want 2", n)
+	}
+}
+
+func TestMemoFilters(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% define "row" %}<td>{% .A %}</td>{% .B %}` + "\n" + `{% end %}{% define "missing" %}{% print .B %}{% end %}`})
+	var filtered int
+	tm := Parse(filepath.Join(dir, "base.tmpl")).OutputFilter(func(out []byte) ([]byte, error) {
+		filtered++
+		return bytes.ToUpper(out), nil
+	}).LineEndings(CRLF)
+
+	ctx := map[string]string{"A": "a", "B": "b"}
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := tm.ExecuteMemo(&buf, "row", ctx); err != nil || buf.String() != "<TD>A</TD>B\r\n" {
+			t.Fatalf("call %d: got %q, %v", i, buf.String(), err)
+		}
+	}
+	if filtered != 2 {
+		t.Errorf("filtered %d times, want 2", filtered)
+	}
+
+	//a remembered output still fails the check for missing values
+	tm.ErrorOnNoValue(true)
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		err := tm.ExecuteMemo(&buf, "missing", nil)
+		if err == nil || !strings.Contains(err.Error(), "&lt;nil&gt;") || buf.Len() != 0 {
+			t.Errorf("call %d: got %q, %v", i, buf.String(), err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package tmplmgr
+
+import "io"
+
+//RenderFunc renders a template with the context to w.
+type RenderFunc func(w io.Writer, ctx interface{}) error
+
+//Middleware wraps a RenderFunc with another, which can change the writer or
+//context passed on, look at or replace the error, or skip calling next.
+type Middleware func(next RenderFunc) RenderFunc
+
+//Use adds middleware around every Execute of the template, such as logging,
+//timing or caching. The first added is outermost and the last added wraps the
+//render itself, which compiles the template when needed and executes it, so
+//middleware runs without any lock of the template held and may call other
+//templates, or this one. Without middleware Execute calls the render
+//directly. ExecuteFragments, ExecuteStreamFragments and ExecuteMemo render
+//without the middleware.
+func (t *Template) Use(mw Middleware) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.middleware = append(t.middleware, mw)
+	return t
+}
+
+//chain returns render wrapped in the middleware. The caller must hold at least
+//the read lock.
+func (t *Template) chain(render RenderFunc) RenderFunc {
+	for i := len(t.middleware) - 1; i >= 0; i-- {
+		render = t.middleware[i](render)
+	}
+	return render
+}
@@ -0,0 +1,128 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io"
+	"regexp"
+	"text/template/parse"
+)
+
+//ExecuteNonce is like Execute, but templates can call nonce to get the given
+//nonce for a Content-Security-Policy, as in <script nonce="{% nonce %}">.
+//Outside of ExecuteNonce, nonce returns the empty string. See NonceTags to
+//add the attribute to every script and style tag automatically.
+func (t *Template) ExecuteNonce(w io.Writer, nonce string, ctx interface{}, globs ...string) (err error) {
+	_, err = t.executeWith(context.Background(), w, ctx, globs, template.FuncMap{
+		"nonce": func() string { return nonce },
+	})
+	return
+}
+
+//NonceTags sets if script and style tags written in the template text without
+//a nonce attribute are given one calling nonce when compiled, so they run
+//under a Content-Security-Policy when executed with ExecuteNonce.
+func (t *Template) NonceTags(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.nonce_tags = on
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//nonceTag matches the start of a script or style tag.
+var nonceTag = regexp.MustCompile(`(?i)<(script|style)[\s/>]`)
+
+//nonceAttr matches a nonce attribute in the text of a tag.
+var nonceAttr = regexp.MustCompile(`(?i)\snonce[\s=/>]`)
+
+//nonceTags inserts a nonce attribute into every script and style tag in the
+//text of the set that does not have one. Tags that were already given one,
+//in a set cloned from a rewritten one, are left alone.
+func nonceTags(tmpl *template.Template) {
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil {
+			continue
+		}
+		walk(x.Tree.Root, func(node parse.Node) {
+			list, ok := node.(*parse.ListNode)
+			if !ok {
+				return
+			}
+
+			var nodes []parse.Node
+			for i, n := range list.Nodes {
+				text, ok := n.(*parse.TextNode)
+				if !ok {
+					nodes = append(nodes, n)
+					continue
+				}
+
+				for {
+					at := nextNonceTag(text.Text, list.Nodes[i+1:])
+					if at < 0 {
+						break
+					}
+
+					//copy the node for the rest so it keeps its tree
+					rest := text.Copy().(*parse.TextNode)
+					rest.Text = append([]byte(`"`), text.Text[at:]...)
+					text.Text = append(text.Text[:at:at], ` nonce="`...)
+					nodes = append(nodes, text, nonceAction())
+					text = rest
+				}
+				nodes = append(nodes, text)
+			}
+			list.Nodes = nodes
+		})
+	}
+}
+
+//nextNonceTag returns the offset in text just after the name of the first
+//script or style tag without a nonce attribute, or -1. Tags not closed within
+//text are looked for in the text nodes following it.
+func nextNonceTag(text []byte, following []parse.Node) int {
+	off := 0
+	for {
+		loc := nonceTag.FindIndex(text[off:])
+		if loc == nil {
+			return -1
+		}
+		name := off + loc[1] - 1
+		off = name
+
+		//collect the tag up to its end, which may be past actions
+		tag := text[name:]
+		end := bytes.IndexByte(tag, '>')
+		for _, n := range following {
+			if end >= 0 {
+				break
+			}
+			tn, ok := n.(*parse.TextNode)
+			if !ok {
+				continue
+			}
+			tag = append(append([]byte{}, tag...), tn.Text...)
+			end = bytes.IndexByte(tag, '>')
+		}
+		if end >= 0 {
+			tag = tag[:end+1]
+		}
+
+		if !nonceAttr.Match(tag) {
+			return name
+		}
+	}
+}
+
+//nonceAction returns an action node calling nonce.
+func nonceAction() parse.Node {
+	trees, err := parse.Parse("nonce", `{{nonce}}`, `{{`, `}}`, requestFuncs)
+	if err != nil {
+		panic(err)
+	}
+	return trees["nonce"].Root.Nodes[0]
+}
@@ -0,0 +1,68 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNonceTags(t *testing.T) {
+	cases := []struct {
+		name string
+		base string
+		out  string
+	}{
+		{"plain", `<script>a()</script><style>b{}</style>`,
+			`<script nonce="n1">a()</script><style nonce="n1">b{}</style>`},
+		{"attributes", `<script src="/a.js" defer></script><STYLE media="print">b{}</STYLE>`,
+			`<script nonce="n1" src="/a.js" defer></script><STYLE nonce="n1" media="print">b{}</STYLE>`},
+		{"has a nonce", `<script nonce="{% nonce %}">a()</script><style type="text/css" NONCE="x">b{}</style>`,
+			`<script nonce="n1">a()</script><style type="text/css" NONCE="x">b{}</style>`},
+		{"attributes from actions", `<script src="{% .Src %}" nonce="{% nonce %}"></script><script src="{% .Src %}"></script>`,
+			`<script src="/a.js" nonce="n1"></script><script nonce="n1" src="/a.js"></script>`},
+		{"not a tag", `<scripts></scripts><p>style</p>`,
+			`<scripts></scripts><p>style</p>`},
+		{"blocks", `{% block "head" . %}<script>a()</script>{% end %}{% if true %}<style>b{}</style>{% end %}`,
+			`<script nonce="n1">a()</script><style nonce="n1">b{}</style>`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"base.tmpl": c.base})
+			tm := Parse(filepath.Join(dir, "base.tmpl")).NonceTags(true)
+
+			var buf strings.Builder
+			if err := tm.ExecuteNonce(&buf, "n1", map[string]string{"Src": "/a.js"}); err != nil || buf.String() != c.out {
+				t.Errorf("got %q, %v, want %q", buf.String(), err, c.out)
+			}
+		})
+	}
+}
+
+func TestNonceTagsGlobs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl":  `<head>{% block "head" . %}{% end %}</head>`,
+		"head.tmpl":  `{% define "head" %}<script src="/a.js"></script>{% end %}`,
+		"other.tmpl": `{% define "head" %}<style>b{}</style>{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).NonceTags(true)
+
+	for _, n := range []string{"n1", "n2"} {
+		var buf strings.Builder
+		if err := tm.ExecuteNonce(&buf, n, nil, filepath.Join(dir, "head.tmpl")); err != nil ||
+			buf.String() != `<head><script nonce="`+n+`" src="/a.js"></script></head>` {
+			t.Errorf("got %q, %v", buf.String(), err)
+		}
+	}
+
+	//a set cloned from a rewritten one gets no second nonce
+	var buf strings.Builder
+	if err := tm.ExecuteNonce(&buf, "n3", nil, filepath.Join(dir, "other.tmpl")); err != nil ||
+		buf.String() != `<head><style nonce="n3">b{}</style></head>` {
+		t.Errorf("got %q, %v", buf.String(), err)
+	}
+	if out, err := tm.ExecuteString(nil, filepath.Join(dir, "head.tmpl")); err != nil || out != `<head><script nonce="" src="/a.js"></script></head>` {
+		t.Errorf("without ExecuteNonce: got %q, %v", out, err)
+	}
+}
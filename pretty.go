@@ -0,0 +1,147 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//Format is the format PrettyPrint reformats the output as.
+type Format int
+
+const (
+	FormatNone Format = iota //leave the output as it is
+	FormatXML                //indent the output as XML
+	FormatJSON               //indent the output as JSON
+)
+
+//PrettyPrint sets the format the output of every Execute is parsed as and
+//indented in, for machine readable output whose layout is otherwise up to the
+//template. Output that is not valid in the format fails the Execute with an
+//error saying why. XML is reformatted element by element, so whitespace
+//around text is not kept. The output is still rendered by html/template, which
+//escapes values for HTML and drops comments and <?xml declarations, so this
+//suits templates that write the markup themselves. Like an OutputFilter, it
+//buffers the output, and it runs after the filters and before LineEndings.
+func (t *Template) PrettyPrint(format Format) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.pretty = format
+	return t
+}
+
+//indent returns out indented in the format.
+func (f Format) indent(out []byte) ([]byte, error) {
+	switch f {
+	case FormatJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, out, "", "  "); err != nil {
+			return nil, fmt.Errorf("pretty print json: %v", err)
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	case FormatXML:
+		res, err := indentXML(out)
+		if err != nil {
+			return nil, fmt.Errorf("pretty print xml: %v", err)
+		}
+		return res, nil
+	}
+	return out, nil
+}
+
+//indentXML returns the XML in out with every element on a line of its own,
+//indented by its depth. Elements holding only text stay on one line.
+func indentXML(out []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var open []string
+	inline := false //the last token written was a start tag or text
+
+	line := func() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat("  ", len(open)))
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(out))
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			line()
+			name := xmlName(tok.Name)
+			buf.WriteString("<" + name)
+			for _, attr := range tok.Attr {
+				buf.WriteString(" " + xmlName(attr.Name) + `="`)
+				xml.EscapeText(&buf, []byte(attr.Value))
+				buf.WriteByte('"')
+			}
+			buf.WriteByte('>')
+			open = append(open, name)
+			inline = true
+		case xml.EndElement:
+			name := xmlName(tok.Name)
+			if len(open) == 0 || open[len(open)-1] != name {
+				l, _ := d.InputPos()
+				return nil, fmt.Errorf("line %d: unexpected </%s>", l, name)
+			}
+			open = open[:len(open)-1]
+			if !inline {
+				line()
+			}
+			buf.WriteString("</" + name + ">")
+			inline = false
+		case xml.CharData:
+			text := bytes.TrimSpace(tok)
+			if len(text) == 0 {
+				continue
+			}
+			if !inline {
+				line()
+			}
+			xml.EscapeText(&buf, text)
+			inline = true
+		case xml.Comment:
+			line()
+			buf.WriteString("<!--" + string(tok) + "-->")
+			inline = false
+		case xml.ProcInst:
+			line()
+			buf.WriteString("<?" + tok.Target)
+			if len(tok.Inst) > 0 {
+				buf.WriteString(" " + string(tok.Inst))
+			}
+			buf.WriteString("?>")
+			inline = false
+		case xml.Directive:
+			line()
+			buf.WriteString("<!" + string(tok) + ">")
+			inline = false
+		}
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("<%s> is never closed", open[len(open)-1])
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+//xmlName returns the name as written, with its prefix.
+func xmlName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
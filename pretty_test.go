@@ -0,0 +1,57 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndentXML(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{`<a><b>text</b><c/></a>`, "<a>\n  <b>text</b>\n  <c></c>\n</a>\n"},
+		{"<a>\n\t<b x=\"1\" y=\"&lt;\">  t  </b>\n</a>", "<a>\n  <b x=\"1\" y=\"&lt;\">t</b>\n</a>\n"},
+		{`<x:a xmlns:x="u"><x:b/></x:a>`, "<x:a xmlns:x=\"u\">\n  <x:b></x:b>\n</x:a>\n"},
+		{`<?xml version="1.0"?><!-- c --><a>1 &amp; 2</a>`, "<?xml version=\"1.0\"?>\n<!-- c -->\n<a>1 &amp; 2</a>\n"},
+	}
+	for _, c := range cases {
+		out, err := indentXML([]byte(c.in))
+		if err != nil || string(out) != c.out {
+			t.Errorf("indentXML(%q) = %q, %v, want %q", c.in, out, err, c.out)
+		}
+	}
+
+	for _, in := range []string{`<a><b></a>`, `<a>`, `</a>`, `<a x=></a>`} {
+		if _, err := indentXML([]byte(in)); err == nil {
+			t.Errorf("indentXML(%q) did not fail", in)
+		}
+	}
+}
+
+func TestPrettyPrintExecute(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"json.tmpl": `{"name": "{% .Name %}", "tags": [{% range $i, $t := .Tags %}{% if $i %},{% end %}"{% $t %}"{% end %}]}`,
+		"xml.tmpl":  `<item><name>{% .Name %}</name><tags>{% range .Tags %}<tag>{% . %}</tag>{% end %}</tags></item>`,
+		"bad.tmpl":  `{"name": {% .Name %}}`,
+	})
+	ctx := map[string]interface{}{"Name": "a<b", "Tags": []string{"x", "y"}}
+
+	//values are still escaped for HTML, as the doc says
+	out, err := Parse(filepath.Join(dir, "json.tmpl")).PrettyPrint(FormatJSON).ExecuteString(ctx)
+	if want := "{\n  \"name\": \"a&lt;b\",\n  \"tags\": [\n    \"x\",\n    \"y\"\n  ]\n}\n"; err != nil || out != want {
+		t.Errorf("json: got %q, %v, want %q", out, err, want)
+	}
+
+	out, err = Parse(filepath.Join(dir, "xml.tmpl")).PrettyPrint(FormatXML).ExecuteString(ctx)
+	if want := "<item>\n  <name>a&lt;b</name>\n  <tags>\n    <tag>x</tag>\n    <tag>y</tag>\n  </tags>\n</item>\n"; err != nil || out != want {
+		t.Errorf("xml: got %q, %v, want %q", out, err, want)
+	}
+
+	var buf strings.Builder
+	err = Parse(filepath.Join(dir, "bad.tmpl")).PrettyPrint(FormatJSON).Execute(&buf, ctx)
+	if err == nil || !strings.Contains(err.Error(), "pretty print json") || buf.Len() != 0 {
+		t.Errorf("invalid json: got %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,100 @@
+//Package prom exposes the compile and execute events of tmplmgr templates as
+//a prometheus.Collector. It lives in its own package so tmplmgr itself does
+//not depend on Prometheus.
+package prom
+
+import (
+	"sync"
+
+	"github.com/go-goods/tmplmgr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+//Collector is a prometheus.Collector reporting compile and execute counts and
+//durations and the cache hit ratio of every template, labeled by base file.
+type Collector struct {
+	compiles   *prometheus.CounterVec
+	compileDur *prometheus.HistogramVec
+	executes   *prometheus.CounterVec
+	executeDur *prometheus.HistogramVec
+	hitRatio   *prometheus.Desc
+
+	mu      sync.Mutex
+	hits    map[string]float64
+	lookups map[string]float64
+}
+
+//NewCollector creates a Collector and subscribes it to the tmplmgr events. It
+//should be created once and registered with a prometheus.Registerer.
+func NewCollector() *Collector {
+	labels := []string{"base"}
+	c := &Collector{
+		compiles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tmplmgr_compiles_total",
+			Help: "Number of template compiles.",
+		}, labels),
+		compileDur: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "tmplmgr_compile_duration_seconds",
+			Help: "Time spent compiling templates.",
+		}, labels),
+		executes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "tmplmgr_executes_total",
+			Help: "Number of template executes.",
+		}, labels),
+		executeDur: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "tmplmgr_execute_duration_seconds",
+			Help: "Time spent executing templates, including any compile.",
+		}, labels),
+		hitRatio: prometheus.NewDesc(
+			"tmplmgr_cache_hit_ratio",
+			"Fraction of executes served from the compiled template cache.",
+			labels, nil,
+		),
+		hits:    map[string]float64{},
+		lookups: map[string]float64{},
+	}
+	tmplmgr.Subscribe(c.observe)
+	return c
+}
+
+//observe records a single tmplmgr event.
+func (c *Collector) observe(ev tmplmgr.Event) {
+	switch ev.Kind {
+	case tmplmgr.CompileEvent:
+		c.compiles.WithLabelValues(ev.Base).Inc()
+		c.compileDur.WithLabelValues(ev.Base).Observe(ev.Duration.Seconds())
+	case tmplmgr.ExecuteEvent:
+		c.executes.WithLabelValues(ev.Base).Inc()
+		c.executeDur.WithLabelValues(ev.Base).Observe(ev.Duration.Seconds())
+
+		c.mu.Lock()
+		c.lookups[ev.Base]++
+		if ev.CacheHit {
+			c.hits[ev.Base]++
+		}
+		c.mu.Unlock()
+	}
+}
+
+//Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.compiles.Describe(ch)
+	c.compileDur.Describe(ch)
+	c.executes.Describe(ch)
+	c.executeDur.Describe(ch)
+	ch <- c.hitRatio
+}
+
+//Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.compiles.Collect(ch)
+	c.compileDur.Collect(ch)
+	c.executes.Collect(ch)
+	c.executeDur.Collect(ch)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for base, n := range c.lookups {
+		ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, c.hits[base]/n, base)
+	}
+}
@@ -0,0 +1,90 @@
+package tmplmgr
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"math/rand"
+	"reflect"
+)
+
+//WithRandom attaches random functions to the template:
+//
+//	rand n          a random int in [0, n)
+//	shuffle items   a shuffled copy of the slice
+//	sample n items  n random items of the slice, in random order
+//
+//They draw from the global source of math/rand, so their output differs
+//between Executes; ExecuteSeed gives them a seeded source for reproducible
+//output in tests.
+func (t *Template) WithRandom() *Template {
+	for name, fnc := range randomFuncs(globalRand{}) {
+		t.Call(name, fnc)
+	}
+	return t
+}
+
+//ExecuteSeed is like Execute but the functions attached by WithRandom draw
+//from a source seeded with seed, so the same seed renders the same output.
+func (t *Template) ExecuteSeed(w io.Writer, seed int64, ctx interface{}, globs ...string) (err error) {
+	_, err = t.executeWith(context.Background(), w, ctx, globs, randomFuncs(rand.New(rand.NewSource(seed))))
+	return
+}
+
+//source is the part of *rand.Rand the random functions use.
+type source interface {
+	Intn(n int) int
+	Shuffle(n int, swap func(i, j int))
+}
+
+//globalRand is the source of the top level functions of math/rand.
+type globalRand struct{}
+
+func (globalRand) Intn(n int) int                     { return rand.Intn(n) }
+func (globalRand) Shuffle(n int, swap func(i, j int)) { rand.Shuffle(n, swap) }
+
+//randomFuncs returns the random functions drawing from src.
+func randomFuncs(src source) template.FuncMap {
+	return template.FuncMap{
+		"rand": func(n int) (int, error) {
+			if n <= 0 {
+				return 0, fmt.Errorf("rand: %d is not positive", n)
+			}
+			return src.Intn(n), nil
+		},
+		"shuffle": func(items interface{}) (interface{}, error) {
+			out, err := copySlice("shuffle", items)
+			if err != nil {
+				return nil, err
+			}
+			src.Shuffle(out.Len(), reflect.Swapper(out.Interface()))
+			return out.Interface(), nil
+		},
+		"sample": func(n int, items interface{}) (interface{}, error) {
+			out, err := copySlice("sample", items)
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 || n > out.Len() {
+				return nil, fmt.Errorf("sample: can't take %d of %d items", n, out.Len())
+			}
+			swap := reflect.Swapper(out.Interface())
+			for i := 0; i < n; i++ {
+				swap(i, i+src.Intn(out.Len()-i))
+			}
+			return out.Slice(0, n).Interface(), nil
+		},
+	}
+}
+
+//copySlice returns a copy of the slice or array items as a slice.
+func copySlice(name string, items interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("%s: can't take items of a %T", name, items)
+	}
+	out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+	reflect.Copy(out, v)
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+)
+
+//registry holds the templates registered by name with Register.
+var registry = struct {
+	sync.RWMutex
+	templates map[string]*Template
+}{templates: map[string]*Template{}}
+
+//Register stores the template under the given name so it can be retrieved
+//anywhere with Get or MustGet. Registering a name again replaces the previous
+//template, which allows templates to be swapped out on a reload.
+func Register(name string, t *Template) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.templates[name] = t
+}
+
+//Get returns the template registered under the given name and whether one was
+//found.
+func Get(name string) (t *Template, ok bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	t, ok = registry.templates[name]
+	return
+}
+
+//MustGet is like Get but panics if no template is registered under the name.
+func MustGet(name string) *Template {
+	t, ok := Get(name)
+	if !ok {
+		panic(fmt.Sprintf("tmplmgr: no template registered as %q", name))
+	}
+	return t
+}
+
+//VerifyAll runs CheckReferences on every registered template, returning an
+//error for every template that fails to compile and for every missing template
+//it invokes, ordered by the name the template is registered under. It is meant
+//as a check before a service starts taking traffic.
+func VerifyAll() (errs []error) {
+	registry.RLock()
+	names := make([]string, 0, len(registry.templates))
+	templates := make(map[string]*Template, len(registry.templates))
+	for name, t := range registry.templates {
+		names = append(names, name)
+		templates[name] = t
+	}
+	registry.RUnlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		missing, err := templates[name].CheckReferences()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			continue
+		}
+		for _, m := range missing {
+			errs = append(errs, fmt.Errorf("%s: missing template %q", name, m))
+		}
+	}
+	return
+}
+
+//SetAllMode sets the mode of every registered template with SetMode, such as
+//to switch them all to Development while debugging and back to Production
+//afterwards. Templates registered later keep their own mode.
+func SetAllMode(mode Mode) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, t := range registry.templates {
+		t.SetMode(mode)
+	}
+}
+
+//ModeReport returns the mode every registered template is in by the name it
+//is registered under. A template is reported in Development only if it
+//compiles on every Execute, which templates read from archives or recompiling
+//in the background never do whatever their mode.
+func ModeReport() map[string]Mode {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	report := make(map[string]Mode, len(registry.templates))
+	for name, t := range registry.templates {
+		t.compile_lock.RLock()
+		report[name] = Mode(t.development())
+		t.compile_lock.RUnlock()
+	}
+	return report
+}
+
+//ReloadOnSignal installs a handler that marks every registered template dirty
+//whenever the process receives sig, such as syscall.SIGHUP, so the next Execute
+//of each recompiles even in Production mode. Only templates registered with
+//Register at the time the signal arrives are affected, and only those parsed
+//from a base file: wrapped and zero Templates are left as they are.
+func ReloadOnSignal(sig os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig)
+	go func() {
+		for range ch {
+			logf("received %v, reloading templates", sig)
+			reloadRegistered()
+		}
+	}()
+}
+
+//reloadRegistered marks every registered template parsed from a base file
+//dirty.
+func reloadRegistered() {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, t := range registry.templates {
+		t.compile_lock.Lock()
+		if t.base != "" && t.wrapped == nil {
+			t.dirty = true
+		}
+		t.compile_lock.Unlock()
+	}
+}
@@ -0,0 +1,51 @@
+package tmplmgr
+
+import (
+	"html/template"
+	"path/filepath"
+	"testing"
+)
+
+//register registers the templates by name for the test.
+func register(t *testing.T, templates map[string]*Template) {
+	t.Helper()
+	for name, tm := range templates {
+		Register(name, tm)
+	}
+	t.Cleanup(func() {
+		registry.Lock()
+		defer registry.Unlock()
+		for name := range templates {
+			delete(registry.templates, name)
+		}
+	})
+}
+
+func TestReloadRegistered(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `parsed`})
+	parsed := Parse(filepath.Join(dir, "base.tmpl"))
+	wrapped := Wrap(template.Must(template.New("wrapped").Parse(`wrapped`)))
+	zero := new(Template)
+	register(t, map[string]*Template{"parsed": parsed, "wrapped": wrapped, "zero": zero})
+
+	for _, tm := range []*Template{parsed, wrapped} {
+		if err := tm.Compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reloadRegistered()
+
+	if !parsed.Dirty() {
+		t.Error("parsed template was not marked dirty")
+	}
+	if wrapped.Dirty() {
+		t.Error("wrapped template was marked dirty")
+	}
+	if out, err := wrapped.ExecuteString(nil); err != nil || out != "wrapped" {
+		t.Errorf("wrapped got %q, %v", out, err)
+	}
+	if zero.dirty {
+		t.Error("zero template was marked dirty")
+	}
+}
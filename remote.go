@@ -0,0 +1,87 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"time"
+)
+
+//ParseRemote is like ParseFS reading the templates from a remote store such as
+//an object store over HTTP: fetch returns the contents of the named file, and
+//list returns the names matching a glob pattern. Remote templates are treated
+//as immutable, so they are only fetched again when the template is compiled
+//again, see RemoteTTL.
+func ParseRemote(fetch func(name string) ([]byte, error), list func(pattern string) ([]string, error), file string) *Template {
+	t := ParseFS(remoteFS{fetch, list}, file)
+	t.immutable = true
+	return t
+}
+
+//RemoteTTL sets how long a compile of the template is used before the next
+//Execute compiles it again, fetching remote templates anew. Zero, the default,
+//means forever.
+func (t *Template) RemoteTTL(d time.Duration) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.remote_ttl = d
+	return t
+}
+
+//expired reports if the compile has outlived the remote TTL. The caller must
+//hold at least the read lock.
+func (t *Template) expired() bool {
+	return t.remote_ttl > 0 && time.Since(t.compiled_at) > t.remote_ttl
+}
+
+//remoteFS is the file system of a remote store.
+type remoteFS struct {
+	fetch func(name string) ([]byte, error)
+	list  func(pattern string) ([]string, error)
+}
+
+func (r remoteFS) Open(name string) (fs.File, error) {
+	data, err := r.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteFile{bytes.NewReader(data), remoteInfo{path.Base(name), int64(len(data))}}, nil
+}
+
+func (r remoteFS) ReadFile(name string) ([]byte, error) {
+	data, err := r.fetch(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+func (r remoteFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return r.list(pattern)
+}
+
+//remoteFile is a fetched file.
+type remoteFile struct {
+	*bytes.Reader
+	info remoteInfo
+}
+
+func (f *remoteFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *remoteFile) Close() error               { return nil }
+
+//remoteInfo describes a fetched file, which has no modification time.
+type remoteInfo struct {
+	name string
+	size int64
+}
+
+func (i remoteInfo) Name() string       { return i.name }
+func (i remoteInfo) Size() int64        { return i.size }
+func (i remoteInfo) Mode() fs.FileMode  { return 0444 }
+func (i remoteInfo) ModTime() time.Time { return time.Time{} }
+func (i remoteInfo) IsDir() bool        { return false }
+func (i remoteInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,111 @@
+package tmplmgr
+
+import (
+	"context"
+	"html/template"
+	"io"
+)
+
+//requestFuncs holds the placeholders for the functions bound for a single
+//Execute call, so templates using them still parse and execute without them.
+//Functions attached with Call take precedence.
+var requestFuncs = template.FuncMap{
+	"nonce":   func() string { return "" },
+	"meta":    metaFunc(nil),
+	"feature": func(string) bool { return false },
+
+	depthEnterFunc: func(string) (bool, error) { return false, nil },
+	depthLeaveFunc: func() (bool, error) { return false, nil },
+
+	accessFunc:      func(...string) bool { return false },
+	accessEnterFunc: func(string, string) bool { return false },
+	accessLeaveFunc: func() bool { return false },
+}
+
+//ExecuteWithData is like Execute, but templates can call meta to look up the
+//values in extra, as in {% meta "request_id" %}, keeping request metadata out
+//of the context. Keys that are not in extra or in the front matter, see
+//WithFrontMatter, give the empty string.
+func (t *Template) ExecuteWithData(w io.Writer, ctx interface{}, extra map[string]interface{}, globs ...string) (err error) {
+	_, err = t.executeWith(context.Background(), w, ctx, globs, template.FuncMap{"meta": t.frontMeta(extra)})
+	return
+}
+
+//ExecuteFeatures is like Execute, but the flags in overrides replace the ones
+//set with Features for this Execute only, such as to turn a beta section on
+//for one request. The other flags keep their values, and templates can call
+//feature even if Features was never called.
+func (t *Template) ExecuteFeatures(w io.Writer, overrides map[string]bool, ctx interface{}, globs ...string) (err error) {
+	_, err = t.executeWith(context.Background(), w, ctx, globs, template.FuncMap{"feature": t.featuresWith(overrides)})
+	return
+}
+
+//featuresWith returns the feature function looking up names in overrides, and
+//then in the flags set with Features. It must only be called while executing,
+//when the read lock is held.
+func (t *Template) featuresWith(overrides map[string]bool) func(string) bool {
+	return func(name string) bool {
+		if on, ok := overrides[name]; ok {
+			return on
+		}
+		return t.features[name]
+	}
+}
+
+//frontMeta returns the meta function looking up keys in extra, and then in the
+//front matter. It must only be called while executing, when the read lock is
+//held.
+func (t *Template) frontMeta(extra map[string]interface{}) func(string) interface{} {
+	return func(key string) interface{} {
+		if v, ok := extra[key]; ok {
+			return v
+		}
+		if v, ok := t.front[key]; ok {
+			return v
+		}
+		return ""
+	}
+}
+
+//metaFunc returns the meta function looking up keys in extra.
+func metaFunc(extra map[string]interface{}) func(string) interface{} {
+	return func(key string) interface{} {
+		if v, ok := extra[key]; ok {
+			return v
+		}
+		return ""
+	}
+}
+
+//requestSet returns a set for the globs of its own with the funcs bound. It
+//is cloned from a copy of the set that is never executed, because a set can
+//not be cloned or have its funcs changed once it has executed. As the clone
+//is escaped again, this costs more than executing a cached set. If dev is set
+//the copy is always parsed again. The caller must hold the read lock.
+func (t *Template) requestSet(globs []string, funcs template.FuncMap, dev bool) (tmpl *template.Template, hit bool, err error) {
+	key := globsKey(globs)
+
+	t.cache_lock.Lock()
+	src, hit := t.sources[key]
+	t.cache_lock.Unlock()
+	if !hit || dev {
+		hit = false
+		if src, err = t.parseExecGlobs(globs); err != nil {
+			return
+		}
+
+		t.cache_lock.Lock()
+		if t.sources == nil {
+			t.sources = map[string]*template.Template{}
+		}
+		t.sources[key] = src
+		t.cache_lock.Unlock()
+	}
+
+	if tmpl, err = src.Clone(); err != nil {
+		return
+	}
+	t.bindSetFuncs(tmpl)
+	tmpl.Funcs(funcs)
+	return
+}
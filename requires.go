@@ -0,0 +1,132 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"reflect"
+	"text/template/parse"
+)
+
+//Requires declares the top level fields or map keys the template expects in
+//its context. Templates that declare requirements have every Execute check the
+//context for them before rendering, returning an error naming the missing
+//ones instead of silently rendering empty values.
+func (t *Template) Requires(fields ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.requires = append(t.requires, fields...)
+	return t
+}
+
+//checkRequires returns an error if ctx lacks any of the required fields. The
+//caller must hold at least the read lock.
+func (t *Template) checkRequires(ctx interface{}) error {
+	if len(t.requires) == 0 {
+		return nil
+	}
+
+	var missing []string
+	v := reflect.ValueOf(ctx)
+	for _, field := range t.requires {
+		if !hasField(v, field) {
+			missing = append(missing, field)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: context %T is missing required fields %v", t.base, ctx, missing)
+	}
+	return nil
+}
+
+//hasField reports if the value has a struct field, method or map key with the
+//given name, following pointers and interfaces.
+func hasField(v reflect.Value, name string) bool {
+	if !v.IsValid() {
+		return false
+	}
+	if v.MethodByName(name).IsValid() {
+		return true
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		f, ok := v.Type().FieldByName(name)
+		return ok && f.PkgPath == ""
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		key := reflect.ValueOf(name).Convert(v.Type().Key())
+		return v.MapIndex(key).IsValid()
+	}
+	return false
+}
+
+//RequireGlobs declares globs that must be passed to every Execute of the
+//template, such as the partials the base invokes that aren't attached with
+//Blocks. An Execute missing any of them fails with an error naming them.
+func (t *Template) RequireGlobs(globs ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.require_globs = append(t.require_globs, globs...)
+	return t
+}
+
+//checkGlobs returns an error if any of the required globs are missing from
+//globs. The caller must hold at least the read lock.
+func (t *Template) checkGlobs(globs []string) error {
+	var missing []string
+outer:
+	for _, req := range t.require_globs {
+		for _, glob := range globs {
+			if glob == req {
+				continue outer
+			}
+		}
+		missing = append(missing, req)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s: missing required globs %v", t.base, missing)
+	}
+	return nil
+}
+
+//RequireNonEmpty sets if Compile fails when the base template has no content
+//other than whitespace and definitions, as with an empty or truncated file,
+//instead of rendering a blank page.
+func (t *Template) RequireNonEmpty(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.require_nonempty = on
+	t.dirty = true
+	return t
+}
+
+//checkNonEmpty returns an error if the base template of the freshly parsed set
+//has no content. The caller must hold at least the read lock.
+func (t *Template) checkNonEmpty(tmpl *template.Template) error {
+	if !t.require_nonempty {
+		return nil
+	}
+	if tmpl.Tree != nil && tmpl.Tree.Root != nil {
+		for _, n := range tmpl.Tree.Root.Nodes {
+			text, ok := n.(*parse.TextNode)
+			if !ok || len(bytes.TrimSpace(text.Text)) > 0 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%s: base template is empty", tmpl.Name())
+}
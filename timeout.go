@@ -0,0 +1,185 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+//CompileTimeout bounds the time a compile of the template may take. If parsing
+//takes longer than d, the compile fails with a timeout error and the partial
+//result is thrown away, leaving the template as it was. Zero, the default,
+//means no limit.
+func (t *Template) CompileTimeout(d time.Duration) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.compile_timeout = d
+	return t
+}
+
+//buildTimeout runs build in a goroutine, giving up on it after the compile
+//timeout or once c is done. The build works on a snapshot of the configuration
+//so a goroutine that is given up on can't race with later changes to the
+//template, and its result is thrown away once it finishes. The caller must
+//hold at least the read lock.
+func (t *Template) buildTimeout(c context.Context, base string) (tmpl *template.Template, err error) {
+	type built struct {
+		tmpl *template.Template
+		err  error
+	}
+
+	//buffered so an abandoned build can still finish and exit
+	done := make(chan built, 1)
+	snap := t.snapshot()
+	go func() {
+		tmpl, err := snap.build(base)
+		done <- built{tmpl, err}
+	}()
+
+	var timeout <-chan time.Time
+	if t.compile_timeout > 0 {
+		timer := time.NewTimer(t.compile_timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b := <-done:
+		if b.err == nil {
+			t.front = snap.front
+		}
+		return b.tmpl, b.err
+	case <-timeout:
+		return nil, fmt.Errorf("compiling %s: timed out after %v: %w", t.base, t.compile_timeout, context.DeadlineExceeded)
+	case <-c.Done():
+		return nil, fmt.Errorf("compiling %s: %w", t.base, c.Err())
+	}
+}
+
+//ExecuteDeadline is like ExecuteAtomic but gives up with a timeout error once d
+//has passed, counting both the compile the Execute may trigger and the render
+//itself, as one budget for the whole request. Nothing is written to w unless
+//it finishes in time, and the error wraps context.DeadlineExceeded if it does
+//not. A render that is given up on stops at its next write; a compile that is
+//given up on is thrown away like one exceeding CompileTimeout, so the next
+//Execute compiles again.
+func (t *Template) ExecuteDeadline(w io.Writer, d time.Duration, ctx interface{}, globs ...string) (err error) {
+	deadline, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	//buffered so an abandoned render can still finish and exit
+	done := make(chan error, 1)
+	buf := &contextBuffer{ctx: deadline}
+	go func() {
+		_, err := t.executeWith(deadline, buf, ctx, globs, nil)
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			return
+		}
+		_, err = buf.WriteTo(w)
+		return
+	case <-deadline.Done():
+		return fmt.Errorf("executing %s: timed out after %v: %w", t.base, d, context.DeadlineExceeded)
+	}
+}
+
+//ExecuteContext is like Execute but stops rendering with the error of c once
+//c is done, such as when the client of a request has gone away, also giving up
+//on a compile the Execute triggered. The render stops at its next write, so
+//whatever was written before stays written; use ExecuteAtomic into a buffer
+//first if that matters. If c carries a mode set with WithMode, the Execute uses
+//it instead of the template's. A Development context parses a set of its own,
+//leaving the compiled template and cached sets to the other Executes.
+func (t *Template) ExecuteContext(c context.Context, w io.Writer, ctx interface{}, globs ...string) (err error) {
+	if err = c.Err(); err != nil {
+		return
+	}
+	_, err = t.executeWith(c, contextWriterTo{w, c}, ctx, globs, nil)
+	return
+}
+
+//contextBuffer is a buffer carrying a context, so the count writer Execute
+//wraps it in fails writes once the context is done.
+type contextBuffer struct {
+	bytes.Buffer
+	ctx context.Context
+}
+
+func (b *contextBuffer) Context() context.Context { return b.ctx }
+
+//contextWriterTo is like contextBuffer for any writer.
+type contextWriterTo struct {
+	io.Writer
+	ctx context.Context
+}
+
+func (w contextWriterTo) Context() context.Context { return w.ctx }
+
+//snapshot returns a copy of the configuration build reads. The caller must
+//hold at least the read lock.
+func (t *Template) snapshot() *Template {
+	snap := &Template{
+		base:   t.base,
+		funcs:  template.FuncMap{},
+		blocks: append([]string(nil), t.blocks...),
+		files:  append([]string(nil), t.files...),
+		lazy:   append([]string(nil), t.lazy...),
+		left:   t.left,
+		right:  t.right,
+		fsys:   t.fsys,
+		trees:  t.trees,
+		trim:   t.trim,
+
+		source_comments: t.source_comments,
+		nonce_tags:      t.nonce_tags,
+		mode:            t.mode,
+		mode_set:        t.mode_set,
+		immutable:       t.immutable,
+
+		require_nonempty: t.require_nonempty,
+		max_depth:        t.max_depth,
+		unmarshal_front:  t.unmarshal_front,
+		trace:            t.trace,
+
+		as_block:   t.as_block,
+		wrapped:    t.wrapped,
+		transforms: append([]func(*template.Template) error(nil), t.transforms...),
+
+		//previewSet resolves the base of snapshots, leaving the log to compile
+		fallback:        t.fallback,
+		fallback_logged: true,
+	}
+	for name, fnc := range t.funcs {
+		snap.funcs[name] = fnc
+	}
+	if t.set_funcs != nil {
+		snap.set_funcs = map[string]func(*template.Template) interface{}{}
+		for name, fnc := range t.set_funcs {
+			snap.set_funcs[name] = fnc
+		}
+	}
+	for _, g := range t.groups {
+		funcs := template.FuncMap{}
+		for name, fnc := range g.funcs {
+			funcs[name] = fnc
+		}
+		if g.funcs == nil {
+			funcs = nil
+		}
+		snap.groups = append(snap.groups, blockGroup{
+			globs: append([]string(nil), g.globs...),
+			left:  g.left,
+			right: g.right,
+			funcs: funcs,
+		})
+	}
+	return snap
+}
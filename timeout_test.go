@@ -0,0 +1,74 @@
+package tmplmgr
+
+import (
+	"context"
+	"errors"
+	"html/template"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteDeadlineCompile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `ok`})
+	release := make(chan struct{})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).TreeTransform(func(*template.Template) error {
+		<-release
+		return nil
+	})
+
+	before := runtime.NumGoroutine()
+	var b strings.Builder
+	err := tm.ExecuteDeadline(&b, 20*time.Millisecond, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a deadline error", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("wrote %q", b.String())
+	}
+
+	//the compile given up on is not kept, and nothing is left running once
+	//it finishes
+	if !tm.Dirty() {
+		t.Fatal("the abandoned compile was kept")
+	}
+	close(release)
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left running, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := tm.ExecuteDeadline(&b, time.Second, nil); err != nil || b.String() != "ok" {
+		t.Fatalf("got %q, %v", b.String(), err)
+	}
+}
+
+func TestExecuteDeadlineRender(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `{% range . %}{% wait %}x{% end %}`})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Call("wait", func() string {
+		time.Sleep(time.Millisecond)
+		return ""
+	})
+	if err := tm.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	before := runtime.NumGoroutine()
+	var b strings.Builder
+	err := tm.ExecuteDeadline(&b, 20*time.Millisecond, make([]int, 1000))
+	if !errors.Is(err, context.DeadlineExceeded) || b.Len() != 0 {
+		t.Fatalf("got %q, %v, want nothing and a deadline error", b.String(), err)
+	}
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left running, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
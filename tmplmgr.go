@@ -1,13 +1,17 @@
 package tmplmgr
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"log"
+	"io/fs"
+	"os"
 	"path/filepath"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //Mode is a type that represents one of two modes, Production or Development.
@@ -30,32 +34,220 @@ func CompileMode(mode Mode) {
 	compile_mode = mode
 }
 
+//modeKey is the context key of the mode set with WithMode.
+type modeKey struct{}
+
+//WithMode returns a copy of parent carrying the mode, which ExecuteContext uses
+//instead of the template's mode. The same Template can then serve cached pages
+//to most requests and always fresh previews to others, which are parsed apart
+//so they don't disturb the cached pages.
+func WithMode(parent context.Context, mode Mode) context.Context {
+	return context.WithValue(parent, modeKey{}, mode)
+}
+
+//SetMode sets the compilation mode of the template, overriding the package's
+//mode set with CompileMode. A mode carried by the context of ExecuteContext
+//still takes precedence.
+func (t *Template) SetMode(mode Mode) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.mode, t.mode_set = mode, true
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
 //Template is the type that represents a template. It is created by using the
 //Parse function and dependencies are attached through Blocks and Call.
 type Template struct {
 	t *template.Template
 
+	//configuration read by build must also be copied by snapshot
+
 	dirty  bool
 	base   string
 	funcs  template.FuncMap
 	blocks []string
+	files  []string
+	lazy   []string
+	groups []blockGroup
+
+	//errors of the pipelines that could not be composed by name, returned by
+	//every compile until the names are attached again, see Pipeline
+	pipeline_errs map[string]error
+
+	//set passed to Wrap, cloned by every compile instead of parsing a base.
+	//It is never executed itself
+	wrapped *template.Template
+
+	//make functions for each compiled set, see CallSet
+	set_funcs map[string]func(*template.Template) interface{}
+
+	//globs parsed in before the ones passed to Execute
+	default_globs []string
+
+	//top level context fields and globs required by Execute
+	requires      []string
+	require_globs []string
+
+	//fail compiles of a base template without content
+	require_nonempty bool
+
+	//values merged beneath map contexts
+	defaults map[string]interface{}
+
+	//flags reported by the feature function
+	features map[string]bool
+
+	//maximum time a compile may take, or 0
+	compile_timeout time.Duration
+
+	//decides which errors ExecuteRetry retries
+	retryable func(error) bool
+
+	//maximum number of bytes written by an Execute, or 0
+	max_output int64
+
+	//shadowed funcs found by the last compile, see Warnings
+	warnings []string
+
+	//decodes the front matter of the base, see WithFrontMatter, and what it
+	//decoded on the last compile
+	unmarshal_front func(data []byte, v interface{}) error
+	front           map[string]interface{}
+
+	//expected output size and its moving average, see HintSize
+	hint_min atomic.Int64
+	hint_avg atomic.Int64
+
+	//wraps every Execute, see Use
+	middleware []Middleware
+
+	//maximum nesting of the context, or 0
+	max_ctx_depth int
+
+	//maximum nesting of template invocations while executing, or 0, and the
+	//hook called around every invocation, see TraceHook
+	max_depth int
+	trace     func(name string) func()
+
+	//log the funcs overriding DefaultFuncs on compile, and why every Execute
+	//compiled or missed the cache
+	warn_shadowing bool
+	explain        bool
+
+	//transform the output of every Execute, see OutputFilter, check it for
+	//missing values, pretty print it and convert its line endings
+	filters      []func([]byte) ([]byte, error)
+	no_value     bool
+	pretty       Format
+	line_endings LineEndingStyle
+
+	//fragments ExecuteFragments checks are valid JSON
+	json_fragments map[string]bool
+
+	//content type of the output, empty to derive it from the base
+	content_type string
+
+	//writer ExecuteTest renders to, nil to discard
+	test_writer io.Writer
+
+	//collapse whitespace in text, mark partials and add nonce attributes at
+	//compile time
+	trim            bool
+	source_comments bool
+	nonce_tags      bool
+
+	//run over every parsed set, see TreeTransform
+	transforms []func(*template.Template) error
+
+	//action delimiters, see delims
+	left, right string
+
+	//file system the templates are read from, nil for the os. immutable
+	//file systems are never recompiled just because of Development mode
+	fsys      fs.FS
+	immutable bool
+
+	//how long a compile of remote templates is used, and when it was made
+	remote_ttl  time.Duration
+	compiled_at time.Time
 
-	//cached compiled glob sets
-	compiled map[string]*template.Template
+	//name the base content is also defined under
+	as_block string
 
+	//mode overriding the package's, if mode_set, see SetMode
+	mode     Mode
+	mode_set bool
+
+	//base file to use when base does not exist
+	fallback        string
+	fallback_logged bool
+
+	//recompile in the background on file changes in Development mode.
+	//stamp describes the files as of the last compile, exec_globs holds
+	//the globs passed to Execute so their files are watched too, and
+	//checking is set while a check is running.
+	background bool
+	stamp      string
+	exec_globs map[string][]string
+	checking   int32
+
+	//cached compiled glob sets. t.t is never executed itself so it can always
+	//be cloned; the set for no globs is cached under the empty key. sources
+	//holds sets that are never executed either, cloned for every Execute that
+	//binds request scoped functions.
+	cache   Cache
+	sources map[string]*template.Template
+
+	//parse trees of the files parsed into the sets, see parseCached
+	trees *treeCache
+
+	//file defining every name of the lazy files since the last compile, see
+	//lazyIndex
+	lazy_index map[string]string
+
+	//outputs remembered by ExecuteMemo and how many to keep, or 0 for the
+	//default
+	memo      map[string][]byte
+	memo_size int
+
+	//compile_lock guards the configuration and t.t. cache_lock serializes
+	//storing new sets in the cache by readers holding the read lock.
 	compile_lock sync.RWMutex
+	cache_lock   sync.Mutex
 }
 
 //Parse creates a new Template with the specified file acting as the base
 //template.
 func Parse(file string) *Template {
 	return &Template{
-		base:     file,
-		funcs:    template.FuncMap{},
-		compiled: map[string]*template.Template{},
+		base:  file,
+		funcs: template.FuncMap{},
+		cache: NewMapCache(),
+		trees: &treeCache{files: map[string]fileTrees{}},
 	}
 }
 
+//Wrap creates a Template from an already parsed template set, for code that
+//builds its templates itself. The set must not have been executed yet, and is
+//not changed. Every compile starts from a clone of it in place of a base file,
+//so Blocks, Call and the other options extend the clone like they extend a
+//parsed base, as do globs passed to Execute. The functions attached with Call
+//and DefaultFuncs are added to the set's own, replacing those of the same
+//name, and blocks are parsed with the delimiters of the Template, not of the
+//set. Development mode does not recompile it.
+func Wrap(tmpl *template.Template) *Template {
+	t := Parse(tmpl.Name())
+	t.immutable = true
+	t.wrapped = tmpl
+	if clone, err := tmpl.Clone(); err == nil {
+		t.wrapped = clone
+	}
+	return t
+}
+
 //Blocks attaches all of the block definitions in files that match the glob 
 //patterns to the template for every Execute call so the base template can
 //evoke them.
@@ -68,26 +260,164 @@ func (t *Template) Blocks(globs ...string) *Template {
 	return t
 }
 
+//Files attaches the block definitions in the named files to the template for
+//every Execute call, like Blocks for single files that don't fit a glob. Files
+//that are the base or matched by the attached globs are only parsed once.
+func (t *Template) Files(files ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.files = append(t.files, files...)
+	t.dirty = true
+	return t
+}
+
+//Merge attaches the blocks, files, block groups and functions of other to the
+//template, leaving out its base, so one Execute sees the partials of both. A
+//function attached to both, with Call or CallSet, keeps the template's own. The
+//blocks are read from the template's file system, not other's.
+func (t *Template) Merge(other *Template) *Template {
+	other.compile_lock.RLock()
+	blocks := append([]string(nil), other.blocks...)
+	files := append([]string(nil), other.files...)
+	var groups []blockGroup
+	for _, g := range other.groups {
+		g.globs = append([]string(nil), g.globs...)
+		if g.funcs != nil {
+			funcs := template.FuncMap{}
+			for name, fnc := range g.funcs {
+				funcs[name] = fnc
+			}
+			g.funcs = funcs
+		}
+		groups = append(groups, g)
+	}
+	funcs := template.FuncMap{}
+	for name, fnc := range other.funcs {
+		funcs[name] = fnc
+	}
+	setFuncs := map[string]func(*template.Template) interface{}{}
+	for name, fnc := range other.set_funcs {
+		setFuncs[name] = fnc
+	}
+	other.compile_lock.RUnlock()
+
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.blocks = append(t.blocks, blocks...)
+	t.files = append(t.files, files...)
+	t.groups = append(t.groups, groups...)
+	for name, fnc := range funcs {
+		if _, ex := t.funcs[name]; ex {
+			continue
+		}
+		t.funcs[name] = fnc
+		if fnc, ok := setFuncs[name]; ok {
+			if t.set_funcs == nil {
+				t.set_funcs = map[string]func(*template.Template) interface{}{}
+			}
+			t.set_funcs[name] = fnc
+		}
+	}
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
 //Call attaches a function to the template under the specified name for every
-//Execute call so the base template can call them.
+//Execute call so the base template can call them. Any cached glob sets are
+//dropped immediately so a stale set built with the old function is never served.
+//Compiled sets hold their own copy of the functions, and Call waits for
+//Executes in progress, so a render always uses the functions of one compile.
 func (t *Template) Call(name string, fnc interface{}) *Template {
 	t.compile_lock.Lock()
 	defer t.compile_lock.Unlock()
 
 	t.funcs[name] = fnc
+	delete(t.set_funcs, name)
+	delete(t.pipeline_errs, name)
 	t.dirty = true
+	t.clearCache()
 	return t
 }
 
+//Const attaches a function taking no arguments and returning value under the
+//name, for values known up front such as the build version, so templates can
+//use {% version %} without every Execute passing it. The value is fixed when
+//Const is called; calling Const again with a new value recompiles, like Call.
+func (t *Template) Const(name string, value interface{}) *Template {
+	return t.Call(name, func() interface{} { return value })
+}
+
 //Compile precompiles the template before Execute. Execute will call Compile if
 //any Execute level globs are passed in, if the Template has had functions added
-//or blocks added since the last Compile, or if the mode is in Development.
+//or blocks added since the last Compile, or if the mode is in Development. A
+//Template not made by Parse or one of its variants has no base to compile and
+//returns an error saying so. The parse trees of every file are kept, so a
+//recompile only parses the files whose contents changed since and copies the
+//trees of the rest.
 func (t *Template) Compile() (err error) {
+	return t.compileContext(context.Background())
+}
+
+//compileContext is like Compile, but gives up on the compile once c is done,
+//leaving the template as it was.
+func (t *Template) compileContext(c context.Context) (err error) {
 	t.compile_lock.Lock()
 	defer t.compile_lock.Unlock()
 
-	log.Printf("compiling %s %s", t.base, t.blocks)
+	return t.compile(c)
+}
+
+//compile does the work of compileContext. The caller must hold the write lock.
+func (t *Template) compile(c context.Context) (err error) {
+	//a zero Template has nothing to parse, and ParseFiles would only complain
+	//about the empty name
+	if t.base == "" && t.wrapped == nil {
+		return errors.New("no base template configured")
+	}
+	if err = t.pipelineErr(); err != nil {
+		return
+	}
+
+	logf("compiling %s %s", t.base, t.blocks)
+
+	start := time.Now()
+	defer func() {
+		emit(Event{Kind: CompileEvent, Base: t.base, Duration: time.Since(start), Err: err})
+	}()
+
+	base, err := t.resolveBase()
+	if err != nil {
+		return
+	}
+
+	if t.background {
+		t.stamp = t.fileStamp()
+	}
+	t.warnings = t.warnShadowed(defaultFuncs())
+
+	var tmpl *template.Template
+	if t.compile_timeout > 0 || c.Done() != nil {
+		tmpl, err = t.buildTimeout(c, base)
+	} else {
+		tmpl, err = t.build(base)
+	}
+	if err != nil {
+		return
+	}
+
+	t.t = tmpl
+	t.dirty = false
+	t.compiled_at = time.Now()
+	t.clearCache()
+	return
+}
 
+//build parses the base and every attached block into a new template set
+//without modifying the Template. The caller must hold at least the read lock.
+func (t *Template) build(base string) (tmpl *template.Template, err error) {
 	//catch the panic from funcs if theres an invalid func map
 	defer func() {
 		if e := recover(); e != nil {
@@ -95,58 +425,471 @@ func (t *Template) Compile() (err error) {
 		}
 	}()
 
-	tmpl := template.New(filepath.Base(t.base))
-	tmpl.Funcs(t.funcs)
-	tmpl.Delims(`{%`, `%}`)
-	tmpl, err = tmpl.ParseFiles(t.base)
+	tmpl = template.New(filepath.Base(base))
+	if t.wrapped != nil {
+		if tmpl, err = t.wrapped.Clone(); err != nil {
+			return
+		}
+	}
+	//Funcs copies the maps, so the set keeps the functions of this compile
+	//however they are changed afterward
+	tmpl.Funcs(requestFuncs).Funcs(defaultFuncs()).Funcs(t.funcs)
+	tmpl.Delims(t.delims())
+	var front map[string]interface{}
+	switch {
+	case t.wrapped != nil:
+		//the clone of the wrapped set stands in for the base
+	case t.unmarshal_front != nil:
+		tmpl, front, err = t.parseFrontMatter(tmpl, base)
+	default:
+		tmpl, err = t.parseCached(tmpl, base)
+	}
 	if err != nil {
 		return
 	}
+	if _, ex := t.funcs["meta"]; front != nil && !ex {
+		tmpl.Funcs(template.FuncMap{"meta": metaFunc(front)})
+	}
+	if err = t.checkNonEmpty(tmpl); err != nil {
+		return
+	}
+
+	if t.as_block != "" && tmpl.Tree != nil {
+		if _, err = tmpl.AddParseTree(t.as_block, tmpl.Tree.Copy()); err != nil {
+			return
+		}
+	}
 
 	for _, glob := range t.blocks {
-		tmpl, err = tmpl.ParseGlob(glob)
+		tmpl, err = t.parseGlobCached(tmpl, glob)
 		if err != nil {
 			return
 		}
 	}
+	if len(t.files) > 0 {
+		var matched []string
+		if matched, err = t.matchGlobs(nil); err != nil {
+			return
+		}
+		if files := t.extraFiles(append(matched, base)); len(files) > 0 {
+			if tmpl, err = t.parseCached(tmpl, files...); err != nil {
+				return
+			}
+		}
+	}
+
+	if err = t.parseGroups(tmpl); err != nil {
+		return
+	}
+
+	if err = t.postParse(tmpl, nil); err != nil {
+		return
+	}
+	t.bindSetFuncs(tmpl)
+	t.front = front
+	return
+}
+
+//AsBlock also defines the top level content of the base file as a block under
+//the given name, so layouts attached with Blocks can invoke it with
+//{% template name . %} without the base file having to define it. The base is
+//still what Execute runs. Blocks defining the same name take precedence.
+func (t *Template) AsBlock(name string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.as_block = name
+	t.dirty = true
+	return t
+}
+
+//BaseFallback sets a file to use as the base template whenever the base file
+//passed to Parse does not exist at compile time.
+func (t *Template) BaseFallback(file string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.fallback = file
+	t.dirty = true
+	return t
+}
+
+//resolveBase returns the file to use as the base template, using the fallback
+//if the base is missing. The caller must hold the write lock.
+func (t *Template) resolveBase() (base string, err error) {
+	base = t.base
+	if t.fallback == "" {
+		return
+	}
+
+	if _, err = t.stat(base); err == nil || !os.IsNotExist(err) {
+		return
+	}
+	if _, err = t.stat(t.fallback); err != nil {
+		err = fmt.Errorf("base %s and fallback %s: %v", t.base, t.fallback, err)
+		return
+	}
+
+	if !t.fallback_logged {
+		logf("%s does not exist, using fallback %s", t.base, t.fallback)
+		t.fallback_logged = true
+	}
+	base = t.fallback
+	return
+}
+
+//postParse runs the configured transformations over a freshly parsed
+//template set, with globs parsed in on top of the attached ones, before it is
+//stored. The caller must hold at least the read lock.
+func (t *Template) postParse(tmpl *template.Template, globs []string) (err error) {
+	if err = t.loadLazy(tmpl); err != nil {
+		return
+	}
+	if t.trim {
+		trimWhitespace(tmpl)
+	}
+	if t.nonce_tags {
+		nonceTags(tmpl)
+	}
+	if t.counted() {
+		depthCounters(tmpl)
+	}
+	if t.source_comments && t.compileMode() == Development {
+		if err = t.sourceComments(tmpl, globs); err != nil {
+			return
+		}
+	}
+	for _, fn := range t.transforms {
+		if err = fn(tmpl); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//TreeTransform adds a function that is run over every freshly parsed template
+//set before it is stored: at the end of Compile and after the globs passed to
+//Execute are parsed, before the set is cached. The function may modify the
+//parse trees of the set through its Templates and their Tree fields. If it
+//returns an error, the compile fails with it. Functions run in the order they
+//were added.
+func (t *Template) TreeTransform(fn func(*template.Template) error) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.transforms = append(t.transforms, fn)
+	t.dirty = true
+	return t
+}
+
+//AddBlock attaches the block definitions in the files matching glob like
+//Blocks, but parses only the new files into the already compiled template
+//instead of recompiling everything. If the template has not been compiled yet
+//or has pending changes, it falls back to a full Compile.
+func (t *Template) AddBlock(glob string) (err error) {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	if t.t == nil || t.dirty {
+		t.blocks = append(t.blocks, glob)
+		t.dirty = true
+		return t.compile(context.Background())
+	}
+
+	tmpl, err := t.t.Clone()
+	if err != nil {
+		return
+	}
+
+	logf("compiling %s %s", t.base, glob)
+	tmpl, err = t.parseGlobCached(tmpl, glob)
+	if err != nil {
+		return
+	}
+	if err = t.postParse(tmpl, []string{glob}); err != nil {
+		return
+	}
+	t.bindSetFuncs(tmpl)
 
 	t.t = tmpl
-	t.dirty = false
-	t.compiled = map[string]*template.Template{}
+	t.blocks = append(t.blocks, glob)
+	t.clearCache()
 	return
 }
 
-func (t *Template) getCachedGlobs(globs []string) (tmpl *template.Template, err error) {
-	key := strings.Join(globs, ",")
-	if cached, ex := t.compiled[key]; ex && compile_mode == Production {
+//DefaultGlobs adds globs that are parsed in on every Execute before the globs
+//passed to it, even when none are, like theme partials every call needs. The
+//sets are cached by the combined globs.
+func (t *Template) DefaultGlobs(globs ...string) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.default_globs = append(t.default_globs, globs...)
+	return t
+}
+
+//withDefaultGlobs returns the globs an Execute with the globs parses in. The
+//caller must hold at least the read lock.
+func (t *Template) withDefaultGlobs(globs []string) []string {
+	if len(t.default_globs) == 0 {
+		return globs
+	}
+	return append(append([]string(nil), t.default_globs...), globs...)
+}
+
+//getCachedGlobs returns the compiled template with the globs attached, building
+//and caching it if needed, or always building it if dev is set. The caller must
+//hold the read lock.
+func (t *Template) getCachedGlobs(globs []string, dev bool) (tmpl *template.Template, hit bool, err error) {
+	key := globsKey(globs)
+
+	cached, ex := t.cache.Get(key)
+	if ex && !dev {
+		tmpl, hit = cached, true
+		return
+	}
+
+	if tmpl, err = t.parseExecGlobs(globs); err != nil {
+		return
+	}
+
+	//another reader may have built the same set while we were parsing, so
+	//keep the first one to make sure everyone executes the same template
+	t.cache_lock.Lock()
+	defer t.cache_lock.Unlock()
+	if cached, ex := t.cache.Get(key); ex && !dev {
 		tmpl = cached
 		return
 	}
+	t.cache.Set(key, tmpl)
+	return
+}
+
+//parseExecGlobs returns a clone of the compiled template with the globs parsed
+//in on top. The caller must hold the read lock.
+func (t *Template) parseExecGlobs(globs []string) (tmpl *template.Template, err error) {
+	if t.background && len(globs) > 0 {
+		t.cache_lock.Lock()
+		t.exec_globs[globsKey(globs)] = globs
+		t.cache_lock.Unlock()
+	}
 
-	tmpl, _ = t.t.Clone()
-	log.Printf("compiling %s", globs)
+	tmpl, err = t.t.Clone()
+	if err != nil {
+		return
+	}
+	if len(globs) > 0 {
+		logf("compiling %s", globs)
+	}
 	for _, glob := range globs {
-		tmpl, err = tmpl.ParseGlob(glob)
+		tmpl, err = t.parseGlobCached(tmpl, glob)
 		if err != nil {
 			return
 		}
 	}
+	if len(globs) > 0 {
+		if err = t.postParse(tmpl, globs); err != nil {
+			return
+		}
+	}
+	t.bindSetFuncs(tmpl)
+	return
+}
+
+//clearCache drops every cached glob set and memoized output. The caller must
+//hold the write lock.
+func (t *Template) clearCache() {
+	t.cache.Clear()
+	t.cache_lock.Lock()
+	t.sources = nil
+	t.memo = nil
+	t.lazy_index = nil
+	t.cache_lock.Unlock()
+}
+
+//Result describes what happened during a single ExecuteResult call.
+type Result struct {
+	Bytes    int64         //number of bytes written to the writer
+	Compiled bool          //whether the call triggered a Compile
+	CacheHit bool          //whether the template set was served from the cache
+	Duration time.Duration //total time spent compiling and executing
+	Template string        //name of the template that was executed
+}
+
+//countWriter wraps an io.Writer counting the bytes written through it. If max
+//is positive, writes past max bytes fail, aborting the execute. If done is not
+//nil, writes fail once it is canceled.
+type countWriter struct {
+	w    io.Writer
+	n    int64
+	max  int64
+	done context.Context
+}
+
+//contextWriter is implemented by writers that carry a context, such as some
+//http.ResponseWriters.
+type contextWriter interface {
+	Context() context.Context
+}
+
+func newCountWriter(w io.Writer, max int64) *countWriter {
+	cw := &countWriter{w: w, max: max}
+	if c, ok := w.(contextWriter); ok {
+		cw.done = c.Context()
+	}
+	return cw
+}
+
+func (c *countWriter) Write(p []byte) (n int, err error) {
+	if c.done != nil {
+		if err = c.done.Err(); err != nil {
+			return
+		}
+	}
+
+	if c.max > 0 && c.n+int64(len(p)) > c.max {
+		n, err = c.w.Write(p[:c.max-c.n])
+		c.n += int64(n)
+		if err == nil {
+			err = fmt.Errorf("template output exceeded %d bytes", c.max)
+		}
+		return
+	}
 
-	t.compiled[key] = tmpl
+	n, err = c.w.Write(p)
+	c.n += int64(n)
 	return
 }
 
+//MaxOutputBytes limits the output of every Execute of the template to n bytes.
+//Once the limit is reached, the execute is aborted with an error. A limit of
+//zero, the default, means no limit.
+func (t *Template) MaxOutputBytes(n int64) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.max_output = n
+	return t
+}
+
 //Execute runs the template with the specified context attaching all the block
 //definitions in the files that match the given globs sending the output to
 //w. Any errors during the compilation of any files that have to be compiled
 //(see the discussion on Modes) or during the execution of the template are
-//returned.
+//returned. If w has a Context method returning a context.Context, the execute
+//is aborted with the context's error at the first write after it is canceled.
 func (t *Template) Execute(w io.Writer, ctx interface{}, globs ...string) (err error) {
-	if t.dirty || compile_mode == Development {
-		err = t.Compile()
+	_, err = t.ExecuteResult(w, ctx, globs...)
+	return
+}
+
+//ExecuteResult behaves like Execute but also reports how many bytes were
+//written, whether a compile was triggered, whether the template set came from
+//the cache and how long the whole call took.
+func (t *Template) ExecuteResult(w io.Writer, ctx interface{}, globs ...string) (res Result, err error) {
+	return t.executeWith(context.Background(), w, ctx, globs, nil)
+}
+
+//executeWith does the work of ExecuteResult, binding the request scoped funcs
+//for this call only if there are any.
+func (t *Template) executeWith(c context.Context, w io.Writer, ctx interface{}, globs []string, funcs template.FuncMap) (res Result, err error) {
+	start := time.Now()
+	defer func() {
+		res.Duration = time.Since(start)
+		emit(Event{Kind: ExecuteEvent, Base: t.base, Duration: res.Duration, CacheHit: res.CacheHit, Err: err})
+	}()
+
+	render := func(w io.Writer, ctx interface{}) error {
+		return t.renderWith(c, globs, &res, funcs, func(tmpl *template.Template) (err error) {
+			if ctx, err = t.prepare(ctx); err != nil {
+				return
+			}
+
+			res.Template = tmpl.Name()
+			if t.filtering() {
+				res.Bytes, err = t.executeFiltered(tmpl, w, ctx)
+				return
+			}
+
+			cw := newCountWriter(w, t.max_output)
+			err = tmpl.Execute(cw, ctx)
+			res.Bytes = cw.n
+			return
+		})
+	}
+
+	t.compile_lock.RLock()
+	render = t.chain(render)
+	t.compile_lock.RUnlock()
+
+	err = render(w, ctx)
+	return
+}
+
+//DebugExplain sets if every Execute logs why it compiled the template, because
+//it was dirty or because of Development mode, and whether it found the set for
+//its globs in the cache, to help find out why templates keep compiling.
+func (t *Template) DebugExplain(on bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.explain = on
+	return t
+}
+
+//render compiles the template if needed and calls fn with the template set
+//for the globs while holding the read lock, recording in res whether it
+//compiled and whether the set came from the cache.
+func (t *Template) render(globs []string, res *Result, fn func(*template.Template) error) (err error) {
+	return t.renderWith(context.Background(), globs, res, nil, fn)
+}
+
+//renderWith is like render, but if funcs is not empty fn is passed a set of
+//its own with the request scoped funcs bound, see requestSet, and the mode is
+//taken from c if it carries one. If only c is in Development mode, fn is
+//passed a set built for it alone, see previewSet.
+func (t *Template) renderWith(c context.Context, globs []string, res *Result, funcs template.FuncMap, fn func(*template.Template) error) (err error) {
+	t.compile_lock.RLock()
+	globs = t.withDefaultGlobs(globs)
+	if t.counted() {
+		var done func()
+		funcs, done = depthFuncs(funcs, t.max_depth, t.trace)
+		defer done()
+	}
+	expired := t.expired()
+	dirty := t.dirty || t.t == nil || expired
+	dev, watch := t.developmentIn(c), t.watching()
+	preview := dev && !t.development()
+	explain := t.explain
+	err = t.checkGlobs(globs)
+	t.compile_lock.RUnlock()
+	if err != nil {
+		return
+	}
+
+	if explain {
+		switch {
+		case preview:
+			logf("%s: private compile: development mode of the context", t.base)
+		case expired:
+			logf("%s: recompile: remote ttl expired", t.base)
+		case dirty:
+			logf("%s: recompile: dirty", t.base)
+		case dev:
+			logf("%s: recompile: development mode", t.base)
+		}
+	}
+
+	if preview {
+		res.Compiled = true
+	} else if dirty || dev {
+		res.Compiled = true
+		err = t.compileContext(c)
 		if err != nil {
 			return
 		}
+	} else if watch {
+		t.checkChanges()
 	}
 
 	//grab a read lock to make sure we dont get a half compiled template
@@ -154,15 +897,65 @@ func (t *Template) Execute(w io.Writer, ctx interface{}, globs ...string) (err e
 	defer t.compile_lock.RUnlock()
 
 	var tmpl *template.Template
-	if len(globs) > 0 {
-		tmpl, err = t.getCachedGlobs(globs)
-		if err != nil {
-			return
+	var hit bool
+	switch {
+	case preview:
+		tmpl, err = t.previewSet(globs, funcs)
+	case len(funcs) > 0:
+		tmpl, hit, err = t.requestSet(globs, funcs, dev)
+	default:
+		tmpl, hit, err = t.getCachedGlobs(globs, dev)
+	}
+	if err != nil {
+		return
+	}
+	res.CacheHit = hit
+
+	if explain {
+		if hit {
+			logf("%s: cache hit", t.base)
+		} else {
+			logf("%s: cache miss: glob set %v", t.base, globs)
 		}
-	} else {
-		tmpl = t.t
 	}
 
-	err = tmpl.Execute(w, ctx)
+	return fn(tmpl)
+}
+
+//previewSet builds a set with the globs parsed in and the funcs bound for an
+//Execute in Development mode only because its context is, from a snapshot of
+//the configuration, so the compiled template and the cached sets the other
+//Executes use are left alone. The caller must hold the read lock.
+func (t *Template) previewSet(globs []string, funcs template.FuncMap) (tmpl *template.Template, err error) {
+	if t.base == "" && t.wrapped == nil {
+		return nil, errors.New("no base template configured")
+	}
+	if err = t.pipelineErr(); err != nil {
+		return
+	}
+
+	snap := t.snapshot()
+	base, err := snap.resolveBase()
+	if err != nil {
+		return
+	}
+	if snap.t, err = snap.build(base); err != nil {
+		return
+	}
+	if tmpl, err = snap.parseExecGlobs(globs); err != nil {
+		return
+	}
+	tmpl.Funcs(funcs)
 	return
 }
+
+//prepare merges the default context into ctx and checks it has the required
+//fields and is not nested too deeply. The caller must hold at least the read
+//lock.
+func (t *Template) prepare(ctx interface{}) (interface{}, error) {
+	ctx = t.withDefaults(ctx)
+	if err := t.checkRequires(ctx); err != nil {
+		return ctx, err
+	}
+	return ctx, t.checkContextDepth(ctx)
+}
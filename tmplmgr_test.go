@@ -0,0 +1,261 @@
+package tmplmgr
+
+import (
+	"context"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWrapExtends(t *testing.T) {
+	dir := t.TempDir()
+	//a file named like the set must not be parsed in its place
+	name := filepath.Join(dir, "page.tmpl")
+	writeFiles(t, dir, map[string]string{
+		"page.tmpl":      `file`,
+		"blocks/a.tmpl":  `{% define "content" %}block {% shout . %}{% end %}`,
+		"execute/b.tmpl": `{% define "content" %}execute{% end %}`,
+		"execute/c.tmpl": `{% define "extra" %}extra{% end %}`,
+	})
+	set := template.Must(template.New(name).Parse(`<p>{{template "content" .}}</p>{{define "content"}}default{{end}}`))
+
+	tm := Wrap(set)
+	if out, err := tm.ExecuteString(nil); err != nil || out != "<p>default</p>" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+
+	tm.Blocks(filepath.Join(dir, "blocks", "*.tmpl")).Call("shout", func(s string) string { return s + "!" })
+	if out, err := tm.ExecuteString("hi"); err != nil || out != "<p>block hi!</p>" {
+		t.Fatalf("with blocks got %q, %v", out, err)
+	}
+	if out, err := tm.ExecuteString(nil, filepath.Join(dir, "execute", "*.tmpl")); err != nil || out != "<p>execute</p>" {
+		t.Fatalf("with globs got %q, %v", out, err)
+	}
+	if err := tm.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	//the wrapped set itself is left alone
+	if set.Lookup("extra") != nil || set.Lookup("content").Tree.Root.String() != "default" {
+		t.Fatal("the wrapped set was changed")
+	}
+}
+
+func TestWithModePreview(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `one`})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	execute := func(c context.Context) string {
+		t.Helper()
+		var b strings.Builder
+		if err := tm.ExecuteContext(c, &b, nil); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	if out := execute(context.Background()); out != "one" {
+		t.Fatalf("got %q", out)
+	}
+	compiled := tm.t
+
+	writeFiles(t, dir, map[string]string{"base.tmpl": `two`})
+	if out := execute(WithMode(context.Background(), Development)); out != "two" {
+		t.Fatalf("preview got %q", out)
+	}
+	if out := execute(context.Background()); out != "one" {
+		t.Fatalf("after the preview got %q", out)
+	}
+	if tm.t != compiled {
+		t.Fatal("the preview recompiled the shared template")
+	}
+	if out := execute(WithMode(context.Background(), Production)); out != "one" {
+		t.Fatalf("Production context got %q", out)
+	}
+}
+
+func TestCallDuringExecute(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% gen %}|{% block "b" . %}{% end %}`,
+		"b.tmpl":    `{% define "b" %}{% gen %}{% end %}`,
+	})
+	gen := func(i int) func() int { return func() int { return i } }
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Call("gen", gen(0))
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for i := 1; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				tm.Call("gen", gen(i))
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				var globs []string
+				if (g+i)%2 == 0 {
+					globs = []string{filepath.Join(dir, "b.tmpl")}
+				}
+				out, err := tm.ExecuteString(nil, globs...)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				parts := strings.Split(out, "|")
+				if globs != nil && parts[0] != parts[1] {
+					t.Errorf("render mixed the funcs of two compiles: %q", out)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(done)
+	<-stopped
+}
+
+func TestCallDropsCachedSets(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% block "b" . %}{% end %}`,
+		"b.tmpl":    `{% define "b" %}{% name %}{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Call("name", func() string { return "old" })
+	glob := filepath.Join(dir, "b.tmpl")
+	if out, err := tm.ExecuteString(nil, glob); err != nil || out != "old" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+
+	tm.Call("name", func() string { return "new" })
+	if out, err := tm.ExecuteString(nil, glob); err != nil || out != "new" {
+		t.Fatalf("stale cached set after Call: got %q, %v", out, err)
+	}
+}
+
+func TestExecuteConcurrentGlobs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"base.tmpl": `{% block "b" . %}none{% end %}`}
+	names := []string{"b0", "b1", "b2", "b3"}
+	for _, name := range names {
+		files[name+".tmpl"] = `{% define "b" %}` + name + `{% end %}`
+	}
+	writeFiles(t, dir, files)
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				want, globs := "none", []string(nil)
+				if k := (g + i) % (len(names) + 1); k < len(names) {
+					want, globs = names[k], []string{filepath.Join(dir, names[k]+".tmpl")}
+				}
+				var buf strings.Builder
+				if err := tm.Execute(&buf, nil, globs...); err != nil || buf.String() != want {
+					t.Errorf("globs %v: got %q, %v", globs, buf.String(), err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestMaxOutputBytesMidRange(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"base.tmpl": `<ul>{% range . %}<li>{% call . %}</li>{% end %}</ul>`})
+	var calls int
+	items := make([]func() string, 1000)
+	for i := range items {
+		items[i] = func() string { calls++; return "item" }
+	}
+	tm := Parse(filepath.Join(dir, "base.tmpl")).MaxOutputBytes(50)
+
+	var buf strings.Builder
+	err := tm.Execute(&buf, items)
+	if err == nil || !strings.Contains(err.Error(), "template output exceeded 50 bytes") {
+		t.Fatalf("got %v", err)
+	}
+	if buf.Len() != 50 || !strings.HasPrefix(buf.String(), "<ul><li>item</li>") {
+		t.Errorf("wrote %q", buf.String())
+	}
+	if calls >= len(items) {
+		t.Errorf("range ran to the end, %d items", calls)
+	}
+
+	if _, err := tm.ExecuteString(items[:2]); err != nil {
+		t.Errorf("small output: %v", err)
+	}
+}
+
+func TestZeroTemplate(t *testing.T) {
+	var tm Template
+	const want = "no base template configured"
+	if err := tm.Compile(); err == nil || err.Error() != want {
+		t.Errorf("Compile: %v", err)
+	}
+	if _, err := tm.ExecuteString(nil); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ExecuteString: %v", err)
+	}
+	var buf strings.Builder
+	if err := tm.Execute(&buf, nil, "*.tmpl"); err == nil || !strings.Contains(err.Error(), want) || buf.Len() != 0 {
+		t.Errorf("Execute with globs: %q, %v", buf.String(), err)
+	}
+}
+
+func TestResetCompiledDuringExecute(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `[{% block "b" . %}none{% end %}]`,
+		"b.tmpl":    `{% define "b" %}b{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl"))
+	glob := filepath.Join(dir, "b.tmpl")
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				tm.ResetCompiled()
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				want, globs := "[none]", []string(nil)
+				if (g+i)%2 == 0 {
+					want, globs = "[b]", []string{glob}
+				}
+				if out, err := tm.ExecuteString(nil, globs...); err != nil || out != want {
+					t.Errorf("got %q, %v", out, err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(done)
+	<-stopped
+}
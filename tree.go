@@ -0,0 +1,60 @@
+package tmplmgr
+
+import (
+	"text/template/parse"
+)
+
+//walk calls fn for the node and every node beneath it in document order.
+func walk(node parse.Node, fn func(parse.Node)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		fn(n)
+		for _, c := range n.Nodes {
+			walk(c, fn)
+		}
+		return
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		fn(n)
+		for _, d := range n.Decl {
+			walk(d, fn)
+		}
+		for _, c := range n.Cmds {
+			walk(c, fn)
+		}
+		return
+	case nil:
+		return
+	}
+
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		walk(n.Pipe, fn)
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walk(a, fn)
+		}
+	case *parse.ChainNode:
+		walk(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walk(n.Pipe, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walk(n.Pipe, fn)
+	walk(n.List, fn)
+	walk(n.ElseList, fn)
+}
@@ -0,0 +1,146 @@
+package tmplmgr
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template/parse"
+)
+
+//treeCache holds the parse trees of every file parsed into the sets of a
+//Template, so a recompile only parses the files that changed since and copies
+//the trees of the rest. It is shared with snapshots of the Template, and
+//replaced by ResetCompiled.
+type treeCache struct {
+	mu    sync.Mutex
+	files map[string]fileTrees
+}
+
+//fileTrees are the trees parsed from a file, with what they were parsed from:
+//the hash of its contents, and the delimiters and function names the parse
+//checked against.
+type fileTrees struct {
+	sum   [sha256.Size]byte
+	sig   string
+	trees map[string]*parse.Tree
+}
+
+//parseCached is like parseFiles, but the trees of files whose contents have
+//not changed since they were last parsed are reused. The contents are compared
+//rather than the modification times, which can miss an edit made within their
+//granularity, so the files are still read but not parsed. tmpl must have the
+//functions and delimiters of the template. Files are told apart by the path
+//they resolve to, and files of immutable file systems such as remote ones are
+//always parsed.
+func (t *Template) parseCached(tmpl *template.Template, files ...string) (*template.Template, error) {
+	if t.trees == nil || t.immutable {
+		return t.parseFiles(tmpl, files...)
+	}
+
+	sig := t.parseSignature()
+	for _, file := range files {
+		data, err := t.readFile(file)
+		if err != nil {
+			if tmpl, err = t.parseFiles(tmpl, file); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		path, sum := t.resolve(file), sha256.Sum256(data)
+		t.trees.mu.Lock()
+		cached, ok := t.trees.files[path]
+		t.trees.mu.Unlock()
+		if !ok || cached.sum != sum || cached.sig != sig {
+			if cached.trees, err = t.parseTrees(file, data); err != nil {
+				return nil, err
+			}
+			cached.sum, cached.sig = sum, sig
+
+			t.trees.mu.Lock()
+			t.trees.files[path] = cached
+			t.trees.mu.Unlock()
+		}
+
+		//the file's own template first, as parsing it would, then its
+		//definitions in a fixed order. Adding the tree of tmpl itself
+		//returns a new Template for it holding the tree, which replaces it
+		name := filepath.Base(file)
+		if tree := cached.trees[name]; tree != nil {
+			x, err := tmpl.AddParseTree(name, tree.Copy())
+			if err != nil {
+				return nil, err
+			}
+			if name == tmpl.Name() {
+				tmpl = x
+			}
+		}
+		names := make([]string, 0, len(cached.trees))
+		for def := range cached.trees {
+			if def != name {
+				names = append(names, def)
+			}
+		}
+		sort.Strings(names)
+		for _, def := range names {
+			if _, err = tmpl.AddParseTree(def, cached.trees[def].Copy()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return tmpl, nil
+}
+
+//parseGlobCached is like parseGlob using parseCached.
+func (t *Template) parseGlobCached(tmpl *template.Template, glob string) (*template.Template, error) {
+	files, err := t.globFiles(glob)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("html/template: pattern matches no files: %#q", glob)
+	}
+	return t.parseCached(tmpl, files...)
+}
+
+//parseTrees parses the contents of the file on their own, returning the trees
+//of the templates it defines by name, including its own under the base of its
+//name.
+func (t *Template) parseTrees(file string, data []byte) (trees map[string]*parse.Tree, err error) {
+	name := filepath.Base(file)
+	set := template.New(name).Funcs(requestFuncs).Funcs(defaultFuncs()).Funcs(t.funcs)
+	set.Delims(t.delims())
+	if _, err = set.Parse(string(data)); err != nil {
+		return
+	}
+
+	trees = map[string]*parse.Tree{}
+	for _, x := range set.Templates() {
+		if x.Tree != nil {
+			trees[x.Name()] = x.Tree
+		}
+	}
+	return
+}
+
+//parseSignature describes the delimiters and function names files are
+//parsed with, which decide whether a parse succeeds.
+func (t *Template) parseSignature() string {
+	left, right := t.delims()
+	names := []string{left, right}
+	for name := range requestFuncs {
+		names = append(names, name)
+	}
+	for name := range defaultFuncs() {
+		names = append(names, name)
+	}
+	for name := range t.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names[2:])
+	return strings.Join(names, "\x00")
+}
@@ -0,0 +1,90 @@
+package tmplmgr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+//writeFiles writes the files with the contents by name into dir.
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTreeCacheLastDefineWins(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `[{% block "a" . %}da{% end %}|{% block "b" . %}db{% end %}]`,
+		"p1.tmpl":   `{% define "a" %}A1{% end %}`,
+		"p2.tmpl":   `{% define "a" %}A2{% end %}{% define "b" %}B2{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Blocks(filepath.Join(dir, "p*.tmpl"))
+
+	for i := 0; i < 2; i++ {
+		tm.ResetCompiled()
+		if out, err := tm.ExecuteString(nil); err != nil || out != "[A2|B2]" {
+			t.Fatalf("compile %d: got %q, %v", i, out, err)
+		}
+	}
+
+	//a cached recompile still lets the later file win
+	writeFiles(t, dir, map[string]string{"p2.tmpl": `{% define "b" %}B3{% end %}{% define "a" %}A3{% end %}`})
+	tm.Blocks()
+	if out, err := tm.ExecuteString(nil); err != nil || out != "[A3|B3]" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+}
+
+func TestTreeCacheReparsesChanged(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `[{% template "a" . %}|{% template "b" . %}]`,
+		"p1.tmpl":   `{% define "a" %}A1{% end %}`,
+		"p2.tmpl":   `{% define "b" %}B1{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).Blocks(filepath.Join(dir, "p*.tmpl"))
+	if out, err := tm.ExecuteString(nil); err != nil || out != "[A1|B1]" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+
+	p1, p2 := filepath.Join(dir, "p1.tmpl"), filepath.Join(dir, "p2.tmpl")
+	tree := func(file, name string) interface{} {
+		return tm.trees.files[file].trees[name]
+	}
+	a, b := tree(p1, "a"), tree(p2, "b")
+
+	//an edit of the same size keeping the modification time is still seen
+	info, err := os.Stat(p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{"p2.tmpl": `{% define "b" %}B2{% end %}`})
+	if err := os.Chtimes(p2, time.Now(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	tm.Blocks()
+	if out, err := tm.ExecuteString(nil); err != nil || out != "[A1|B2]" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if tree(p1, "a") != a {
+		t.Error("unchanged file was parsed again")
+	}
+	if tree(p2, "b") == b {
+		t.Error("changed file was not parsed again")
+	}
+
+	tm.ResetCompiled()
+	if len(tm.trees.files) != 0 {
+		t.Errorf("ResetCompiled kept %d files of trees", len(tm.trees.files))
+	}
+}
@@ -0,0 +1,34 @@
+package tmplmgr
+
+import "io"
+
+//TypedTemplate wraps a Template so its context has to be a T, catching a
+//wrong context at compile time instead of when the template executes.
+type TypedTemplate[T any] struct {
+	t *Template
+}
+
+//Typed returns a TypedTemplate for t taking contexts of type T.
+func Typed[T any](t *Template) *TypedTemplate[T] {
+	return &TypedTemplate[T]{t: t}
+}
+
+//Template returns the wrapped Template.
+func (tt *TypedTemplate[T]) Template() *Template {
+	return tt.t
+}
+
+//Execute is like Template.Execute with a context of type T.
+func (tt *TypedTemplate[T]) Execute(w io.Writer, ctx T, globs ...string) error {
+	return tt.t.Execute(w, ctx, globs...)
+}
+
+//ExecuteString is like Template.ExecuteString with a context of type T.
+func (tt *TypedTemplate[T]) ExecuteString(ctx T, globs ...string) (string, error) {
+	return tt.t.ExecuteString(ctx, globs...)
+}
+
+//ExecuteBytes is like Template.ExecuteBytes with a context of type T.
+func (tt *TypedTemplate[T]) ExecuteBytes(ctx T, globs ...string) ([]byte, error) {
+	return tt.t.ExecuteBytes(ctx, globs...)
+}
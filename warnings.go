@@ -0,0 +1,70 @@
+package tmplmgr
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+//Warnings returns what looks suspicious about the template without failing
+//over it, so a dashboard can show a template is serving but needs a look: the
+//funcs attached with Call overriding DefaultFuncs as of the last Compile,
+//LazyBlocks globs matching no files, and names defined by more than one of the
+//files attached with Blocks or LazyBlocks, where only one of the definitions
+//is used. A name the base defines and a block file redefines is the usual way
+//to fill a block, so it is not warned about. The files are globbed and scanned
+//for definitions when Warnings is called, not on every compile, so they are as
+//they are now. Files read by ParseRemote are not scanned, so they are not
+//fetched twice.
+func (t *Template) Warnings() []string {
+	t.compile_lock.RLock()
+	defer t.compile_lock.RUnlock()
+
+	return append(append([]string(nil), t.warnings...), t.fileWarnings()...)
+}
+
+//fileWarnings returns the warnings about the attached files. Files that can't
+//be read are left to the compile to report. The caller must hold at least the
+//read lock.
+func (t *Template) fileWarnings() (warnings []string) {
+	var blocks []string
+	for _, glob := range t.blocks {
+		files, _ := t.globFiles(glob)
+		blocks = append(blocks, files...)
+	}
+	warnings = append(warnings, t.duplicateDefines(blocks)...)
+
+	var lazy []string
+	for _, glob := range t.lazy {
+		files, err := t.globFiles(glob)
+		if err == nil && len(files) == 0 {
+			warnings = append(warnings, fmt.Sprintf("lazy blocks %s match no files", glob))
+		}
+		lazy = append(lazy, files...)
+	}
+	warnings = append(warnings, t.duplicateDefines(lazy)...)
+	return
+}
+
+//duplicateDefines returns a warning for every name defined by more than one of
+//the files.
+func (t *Template) duplicateDefines(files []string) (warnings []string) {
+	if _, remote := t.fsys.(remoteFS); remote {
+		return
+	}
+
+	seen := map[string]string{}
+	for _, file := range files {
+		names, err := t.defines(file)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			if first, ok := seen[name]; ok && filepath.Clean(first) != filepath.Clean(file) {
+				warnings = append(warnings, fmt.Sprintf("%s is defined in both %s and %s", name, first, file))
+				continue
+			}
+			seen[name] = file
+		}
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": `{% block "a" . %}x{% end %}`,
+		"p1.tmpl":   `{% define "a" %}1{% end %}{% define "z" %}{% end %}`,
+		"p2.tmpl":   `{% define "z" %}2{% end %}`,
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).
+		Blocks(filepath.Join(dir, "p*.tmpl")).
+		LazyBlocks(filepath.Join(dir, "none", "*"))
+	if err := tm.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := tm.Warnings()
+	all := strings.Join(w, "\n")
+	if len(w) != 2 || !strings.Contains(all, "z is defined in both") || !strings.Contains(all, "match no files") {
+		t.Fatalf("got %q", w)
+	}
+
+	//the files are scanned as they are when asked
+	writeFiles(t, dir, map[string]string{"p2.tmpl": `{% define "y" %}2{% end %}`})
+	if w := tm.Warnings(); len(w) != 1 {
+		t.Fatalf("after the edit got %q", w)
+	}
+}
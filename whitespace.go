@@ -0,0 +1,127 @@
+package tmplmgr
+
+import (
+	"bytes"
+	"html/template"
+	"text/template/parse"
+)
+
+//TrimWhitespace sets if the template collapses the whitespace in its text at
+//compile time. Runs of whitespace are collapsed to a single newline if they
+//contain one, and a single space otherwise, so the rendered page looks the same
+//in a browser while dropping the indentation. The contents of pre, textarea,
+//script and style elements are left untouched. Only the literal text of the
+//templates is affected, never the values they output.
+func (t *Template) TrimWhitespace(trim bool) *Template {
+	t.compile_lock.Lock()
+	defer t.compile_lock.Unlock()
+
+	t.trim = trim
+	t.dirty = true
+	t.clearCache()
+	return t
+}
+
+//rawElements are the elements whose contents are never trimmed.
+var rawElements = []string{"pre", "textarea", "script", "style"}
+
+//trimmer collapses whitespace in text nodes, remembering across nodes if it is
+//inside of a raw element.
+type trimmer struct {
+	raw string
+}
+
+//trimWhitespace collapses the whitespace in every text node of the set.
+func trimWhitespace(tmpl *template.Template) {
+	for _, x := range tmpl.Templates() {
+		if x.Tree == nil {
+			continue
+		}
+		tr := &trimmer{}
+		walk(x.Tree.Root, func(node parse.Node) {
+			if n, ok := node.(*parse.TextNode); ok {
+				n.Text = tr.trim(n.Text)
+			}
+		})
+	}
+}
+
+//trim collapses the whitespace in text outside of raw elements.
+func (tr *trimmer) trim(text []byte) []byte {
+	var out bytes.Buffer
+	for len(text) > 0 {
+		if tr.raw != "" {
+			end := indexTag(text, "</"+tr.raw)
+			if end < 0 {
+				out.Write(text)
+				break
+			}
+			end += len(tr.raw) + 2
+			out.Write(text[:end])
+			text, tr.raw = text[end:], ""
+			continue
+		}
+
+		start, name := len(text), ""
+		for _, el := range rawElements {
+			if i := indexTag(text, "<"+el); i >= 0 && i < start {
+				start, name = i, el
+			}
+		}
+		collapse(&out, text[:start])
+		if name == "" {
+			break
+		}
+		start += len(name) + 1
+		out.Write(text[start-len(name)-1 : start])
+		text, tr.raw = text[start:], name
+	}
+	return out.Bytes()
+}
+
+//indexTag returns the index of the tag opening in text, matching case
+//insensitively and requiring the name to end there, or -1.
+func indexTag(text []byte, tag string) int {
+	lower := bytes.ToLower(text)
+	for off := 0; ; {
+		i := bytes.Index(lower[off:], []byte(tag))
+		if i < 0 {
+			return -1
+		}
+		i += off
+		end := i + len(tag)
+		if end == len(text) || text[end] == '>' || text[end] == '/' || isSpace(text[end]) {
+			return i
+		}
+		off = end
+	}
+}
+
+//collapse writes text to out with each run of whitespace replaced by a newline
+//if the run contains one, or a space otherwise.
+func collapse(out *bytes.Buffer, text []byte) {
+	for i := 0; i < len(text); {
+		if !isSpace(text[i]) {
+			out.WriteByte(text[i])
+			i++
+			continue
+		}
+		sep := byte(' ')
+		for ; i < len(text) && isSpace(text[i]); i++ {
+			if text[i] == '\n' {
+				sep = '\n'
+			}
+		}
+		out.WriteByte(sep)
+	}
+}
+
+//isSpace reports if b is ASCII whitespace. Other bytes may be part of a
+//multibyte UTF-8 sequence and are never collapsed.
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\f':
+		return true
+	}
+	return false
+}
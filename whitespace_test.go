@@ -0,0 +1,51 @@
+package tmplmgr
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTrimmer(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"<p>  a \t b  </p>", "<p> a b </p>"},
+		{"<ul>\n    <li>a</li>\n\n    <li>b</li>\n</ul>", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>"},
+		{"<pre>  a\n  b</pre>  <p>", "<pre>  a\n  b</pre> <p>"},
+		{"<PRE class=x>  a  </Pre>  ", "<PRE class=x>  a  </Pre> "},
+		{"<prefix>  a  </prefix>", "<prefix> a </prefix>"},
+		{"<textarea>  a  </textarea><script>  if (a)  b  </script>", "<textarea>  a  </textarea><script>  if (a)  b  </script>"},
+		{"é     é", "é   é"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		tr := &trimmer{}
+		if got := string(tr.trim([]byte(c.in))); got != c.out {
+			t.Errorf("trim(%q) = %q, want %q", c.in, got, c.out)
+		}
+	}
+
+	//a raw element spans the text nodes around actions
+	tr := &trimmer{}
+	if got := string(tr.trim([]byte("<pre>  a  "))); got != "<pre>  a  " {
+		t.Errorf("got %q", got)
+	}
+	if got := string(tr.trim([]byte("  b  </pre>  c  "))); got != "  b  </pre> c " {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTrimWhitespaceExecute(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.tmpl": "<div>\n    <pre>  {% .Code %}  </pre>\n    <p>  {% .Text %}  </p>\n</div>",
+	})
+	tm := Parse(filepath.Join(dir, "base.tmpl")).TrimWhitespace(true)
+	out, err := tm.ExecuteString(map[string]string{"Code": "x  y", "Text": "a   b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<div>\n<pre>  x  y  </pre>\n<p> a   b </p>\n</div>"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}